@@ -1,43 +1,330 @@
 package dice
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"math"
+)
 
 type node struct {
 	token token
 	left  *node
 	right *node
+	args  []*node   // arguments of a function call
+	term  *diceTerm // dice term parsed from the token of a dice node, parsed from the token when nil
 }
 
-func walk(root *node) (int, error) {
+// walk evaluates the tree and returns a Result tree that mirrors it
+func walk(root *node, opts options) (*Result, error) {
+	if opts.rolled == nil {
+		// the outermost call counts the dice of the whole expression against the limit
+		opts.rolled = new(int)
+	}
+	if err := opts.ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	if root.token.kind == eof {
-		return 0, nil
+		return &Result{Kind: LiteralResult, Value: intValue(0)}, nil
+	}
+
+	if root.token.kind == unaryOperator {
+		if root.right == nil {
+			return nil, fmt.Errorf("root node is a unary operator node with a nil right.")
+		}
+		rhs, err := walk(root.right, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := unary(root.token.value, rhs.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: UnaryResult, Expression: root.token.value, Value: v, Children: []*Result{rhs}}, nil
 	}
 
 	if root.token.kind == operator {
 		if root.left == nil || root.right == nil {
-			return 0, fmt.Errorf("root node is an operator node with a nil right or left.")
+			return nil, fmt.Errorf("root node is an operator node with a nil right or left.")
 		}
-		lhs, err := walk(root.left)
+		lhs, err := walk(root.left, opts)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
-		rhs, err := walk(root.right)
+
+		// only the branch that is picked is evaluated, so dice in the other branch are never rolled
+		switch root.token.value {
+		case "?":
+			return conditional(root, lhs, opts)
+		case "&&", "||":
+			if !lhs.Value.IsBool() {
+				return nil, errorf(ErrType, "Operator %s needs boolean operands but found %s.", root.token.value, lhs.Value)
+			}
+			if lhs.Value.Bool() == (root.token.value == "||") {
+				return &Result{Kind: OperatorResult, Expression: root.token.value, Value: lhs.Value, Children: []*Result{lhs}}, nil
+			}
+		}
+
+		rhs, err := walk(root.right, opts)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 
-		switch root.token.value {
-		case "+":
-			return lhs + rhs, nil
-		case "-":
-			return lhs - rhs, nil
-		case "*":
-			return lhs * rhs, nil
-		case "/":
-			return lhs / rhs, nil
-		default:
-			return 0, fmt.Errorf("Invalid operator value found for token. Value was %s but should be +, -, *, or /.", root.token.value)
-		}
-	}
-	return root.token.evaluate()
+		v, err := binary(root.token.value, lhs.Value, rhs.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: OperatorResult, Expression: root.token.value, Value: v, Children: []*Result{lhs, rhs}}, nil
+	}
+
+	if root.token.kind == identifier {
+		return call(root, opts)
+	}
+
+	if root.token.kind == dice {
+		var roll diceRoll
+		var err error
+		if root.term != nil {
+			roll, err = root.term.roll(opts)
+		} else {
+			roll, err = root.token.roll(opts)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Kind: DiceResult, Expression: root.token.value, Value: intValue(roll.total), Dice: roll.dice, Pool: roll.pool}, nil
+	}
+
+	n, err := root.token.evaluate(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Kind: LiteralResult, Expression: root.token.value, Value: intValue(n)}, nil
+}
+
+func unary(operator string, rhs Value) (Value, error) {
+	if operator == "!" {
+		if !rhs.IsBool() {
+			return Value{}, errorf(ErrType, "Operator ! needs a boolean operand but found %s.", rhs)
+		}
+		return boolValue(!rhs.Bool()), nil
+	}
+
+	if rhs.IsBool() {
+		return Value{}, errorf(ErrType, "Operator %s needs an integer operand but found %s.", operator, rhs)
+	}
+
+	switch operator {
+	case "+":
+		return rhs, nil
+	case "-":
+		if rhs.Int() == math.MinInt {
+			return Value{}, errorf(ErrOverflow, "Integer overflow in -(%d).", rhs.Int())
+		}
+		return intValue(-rhs.Int()), nil
+	default:
+		return Value{}, errorf(ErrUnknownOperator, "Invalid unary operator value found for token. Value was %s but should be +, - or !.", operator)
+	}
+}
+
+// conditional evaluates the branch of a cond ? then : else node picked by the already evaluated cond
+func conditional(root *node, cond *Result, opts options) (*Result, error) {
+	branches := root.right
+	if branches.token.value != ":" || branches.left == nil || branches.right == nil {
+		return nil, fmt.Errorf("Conditional node must have a : node with both branches on its right.")
+	}
+
+	if !cond.Value.IsBool() {
+		return nil, conditionTypeError(root.left, cond.Value)
+	}
+
+	branch := branches.right
+	if cond.Value.Bool() {
+		branch = branches.left
+	}
+
+	picked, err := walk(branch, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Kind: ConditionalResult, Expression: root.token.value, Value: picked.Value, Children: []*Result{cond, picked}}, nil
+}
+
+// conditionTypeError returns the error for a ?: whose condition evaluated to the integer v, with a hint when the
+// condition was written like the success pool 1d20>=15
+func conditionTypeError(cond *node, v Value) error {
+	if isSuccessPool(cond) {
+		return errorf(ErrType, "Condition of ?: must be a boolean but found %s. %s", v, successPointHint)
+	}
+	return errorf(ErrType, "Condition of ?: must be a boolean but found %s.", v)
+}
+
+func binary(operator string, lhs Value, rhs Value) (Value, error) {
+	switch operator {
+	case "==", "!=":
+		if lhs.IsBool() != rhs.IsBool() {
+			return Value{}, errorf(ErrType, "Operator %s can't compare %s with %s. Both operands must be integers or both booleans.", operator, lhs, rhs)
+		}
+		return boolValue((lhs == rhs) == (operator == "==")), nil
+	case "&&", "||":
+		if !rhs.IsBool() {
+			return Value{}, errorf(ErrType, "Operator %s needs boolean operands but found %s.", operator, rhs)
+		}
+		return rhs, nil
+	}
+
+	if lhs.IsBool() || rhs.IsBool() {
+		return Value{}, errorf(ErrType, "Operator %s needs integer operands but found %s and %s.", operator, lhs, rhs)
+	}
+	l, r := lhs.Int(), rhs.Int()
+
+	switch operator {
+	case "+":
+		n, err := add(l, r)
+		return intValue(n), err
+	case "-":
+		if r == math.MinInt {
+			if l >= 0 {
+				return Value{}, errorf(ErrOverflow, "Integer overflow in %d - %d.", l, r)
+			}
+			return intValue(l - r), nil
+		}
+		n, err := add(l, -r)
+		return intValue(n), err
+	case "*":
+		n, err := multiply(l, r)
+		return intValue(n), err
+	case "/":
+		if err := checkDivision(l, r); err != nil {
+			return Value{}, err
+		}
+		return intValue(l / r), nil
+	case "%":
+		if r == 0 {
+			return Value{}, errorf(ErrDivisionByZero, "Modulo by zero.")
+		}
+		return intValue(l % r), nil
+	case "^", "**":
+		n, err := power(l, r)
+		return intValue(n), err
+	case "<":
+		return boolValue(l < r), nil
+	case "<=":
+		return boolValue(l <= r), nil
+	case ">":
+		return boolValue(l > r), nil
+	case ">=":
+		return boolValue(l >= r), nil
+	default:
+		return Value{}, errorf(ErrUnknownOperator, "Invalid operator value found for token. Value was %s but should be one of +, -, *, /, %%, ^, **, <, <=, >, >=, ==, !=, &&, || or ?.", operator)
+	}
+}
+
+// call evaluates the arguments of a function call node and passes them to the function
+func call(root *node, opts options) (*Result, error) {
+	if err := checkArguments(root.token.value, root.args); err != nil {
+		return nil, err
+	}
+	fn := functions[root.token.value]
+	result := &Result{Kind: CallResult, Expression: root.token.value}
+
+	if fn.divide != nil {
+		division := root.args[0]
+		if division.left == nil || division.right == nil {
+			return nil, fmt.Errorf("root node is an operator node with a nil right or left.")
+		}
+		lhs, err := walkInt(division.left, opts)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := walkInt(division.right, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := fn.divide(lhs.Value.Int(), rhs.Value.Int())
+		if err != nil {
+			return nil, err
+		}
+		quotient := &Result{Kind: OperatorResult, Expression: "/", Value: intValue(lhs.Value.Int() / rhs.Value.Int()), Children: []*Result{lhs, rhs}}
+		result.Value = intValue(n)
+		result.Children = []*Result{quotient}
+		return result, nil
+	}
+
+	args := make([]int, len(root.args))
+	for _, arg := range root.args {
+		child, err := walkInt(arg, opts)
+		if err != nil {
+			return nil, err
+		}
+		args[len(result.Children)] = child.Value.Int()
+		result.Children = append(result.Children, child)
+	}
+
+	n, err := fn.call(args)
+	if err != nil {
+		return nil, err
+	}
+	result.Value = intValue(n)
+	return result, nil
+}
+
+// walkInt walks the tree and returns an error when it evaluates to a boolean
+func walkInt(root *node, opts options) (*Result, error) {
+	result, err := walk(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Value.IsBool() {
+		return nil, errorf(ErrType, "Expected an integer but found %s.", result.Value)
+	}
+	return result, nil
+}
+
+// power raises base to a non-negative integer exponent by repeated squaring
+func power(base int, exponent int) (int, error) {
+	if exponent < 0 {
+		return 0, errorf(errors.ErrUnsupported, "Negative exponent %d is not supported.", exponent)
+	}
+
+	b, e := base, exponent
+	result := 1
+	var err error
+	for e > 0 {
+		if e&1 == 1 {
+			if result, err = multiply(result, b); err != nil {
+				return 0, errorf(ErrOverflow, "Integer overflow in %d ^ %d.", base, exponent)
+			}
+		}
+		e >>= 1
+		// only square the base when it is used again, so the last square can't overflow
+		if e > 0 {
+			if b, err = multiply(b, b); err != nil {
+				return 0, errorf(ErrOverflow, "Integer overflow in %d ^ %d.", base, exponent)
+			}
+		}
+	}
+	return result, nil
+}
+
+// add returns l + r or an error wrapping ErrOverflow when the sum doesn't fit in an int
+func add(l int, r int) (int, error) {
+	if (r > 0 && l > math.MaxInt-r) || (r < 0 && l < math.MinInt-r) {
+		return 0, errorf(ErrOverflow, "Integer overflow in %d + %d.", l, r)
+	}
+	return l + r, nil
+}
+
+// multiply returns l * r or an error wrapping ErrOverflow when the product doesn't fit in an int
+func multiply(l int, r int) (int, error) {
+	if l == 0 || r == 0 {
+		return 0, nil
+	}
+	n := l * r
+	if n/r != l || (l == -1 && r == math.MinInt) || (r == -1 && l == math.MinInt) {
+		return 0, errorf(ErrOverflow, "Integer overflow in %d * %d.", l, r)
+	}
+	return n, nil
 }
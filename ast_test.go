@@ -9,29 +9,47 @@ type walkTestCase struct {
 }
 
 var invalidWalkTestCases = []walkTestCase{
-	{"Operator token without left returns an error", node{token{operator, "+"}, nil, &node{token{literal, "1"}, nil, nil}}, 0},
-	{"Operator token without right returns an error", node{token{operator, "+"}, &node{token{literal, "1"}, nil, nil}, nil}, 0},
-	{"Recursively, when node is missing left returns an error", node{token{literal, "+"}, &node{token{literal, "1"}, &node{token{literal, "1"}, nil, nil}, nil}, &node{token{literal, "1"}, nil, nil}}, 0},
-	{"Recursively, when node is missing right returns an error", node{token{literal, "+"}, &node{token{literal, "1"}, nil, nil}, &node{token{literal, "1"}, &node{token{literal, "1"}, nil, nil}, nil}}, 0},
-	{"Malformed operator ** token an error", node{token{operator, "**"}, &node{token{literal, "3"}, nil, nil}, &node{token{literal, "5"}, nil, nil}}, 0},
+	{"Operator token without left returns an error", node{token{operator, "+", 0, 0}, nil, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Operator token without right returns an error", node{token{operator, "+", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil, nil}, 0},
+	{"Recursively, when node is missing left returns an error", node{token{literal, "+", 0, 0}, &node{token{literal, "1", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil, nil}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Recursively, when node is missing right returns an error", node{token{literal, "+", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "1", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil, nil}, nil, nil}, 0},
+	{"Conditional operator token without : node returns an error", node{token{operator, "?", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Unary operator ! token with integer right returns an error", node{token{unaryOperator, "!", 0, 0}, nil, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Unary operator token without right returns an error", node{token{unaryOperator, "-", 0, 0}, nil, nil, nil, nil}, 0},
+	{"Unary operator token with a binary only operator returns an error", node{token{unaryOperator, "*", 0, 0}, nil, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Function call with unknown function returns an error", node{token{identifier, "sqrt", 0, 0}, nil, nil, []*node{{token{literal, "4", 0, 0}, nil, nil, nil, nil}}, nil}, 0},
+	{"Function call with wrong number of arguments returns an error", node{token{identifier, "abs", 0, 0}, nil, nil, []*node{}, nil}, 0},
+	{"Malformed operator +- token an error", node{token{operator, "+-", 0, 0}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "5", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Operator / token with zero right returns an error", node{token{operator, "/", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "0", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Operator % token with zero right returns an error", node{token{operator, "%", 0, 0}, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "0", 0, 0}, nil, nil, nil, nil}, nil, nil}, 0},
+	{"Operator ^ token with negative right returns an error", node{token{operator, "^", 0, 0}, &node{token{literal, "2", 0, 0}, nil, nil, nil, nil}, &node{token{unaryOperator, "-", 0, 0}, nil, &node{token{literal, "1", 0, 0}, nil, nil, nil, nil}, nil, nil}, nil, nil}, 0},
 }
 
 var validWalkTestCases = []walkTestCase{
-	{"EOF token returns 0", node{token{eof, ""}, nil, nil}, 0},
-	{"Operator + token returns left plus right", node{token{operator, "+"}, &node{token{literal, "3"}, nil, nil}, &node{token{literal, "5"}, nil, nil}}, 8},
-	{"Operator - token returns left minus right", node{token{operator, "-"}, &node{token{literal, "3"}, nil, nil}, &node{token{literal, "5"}, nil, nil}}, -2},
-	{"Operator * token returns left multiplied by right", node{token{operator, "*"}, &node{token{literal, "3"}, nil, nil}, &node{token{literal, "5"}, nil, nil}}, 15},
-	{"Operator / token returns left divided right", node{token{operator, "/"}, &node{token{literal, "10"}, nil, nil}, &node{token{literal, "5"}, nil, nil}}, 2},
-	{"Division of 2 ints rounds down.", node{token{operator, "/"}, &node{token{literal, "3"}, nil, nil}, &node{token{literal, "2"}, nil, nil}}, 2},
+	{"EOF token returns 0", node{token{eof, "", 0, 0}, nil, nil, nil, nil}, 0},
+	{"Operator + token returns left plus right", node{token{operator, "+", 0, 0}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "5", 0, 0}, nil, nil, nil, nil}, nil, nil}, 8},
+	{"Operator - token returns left minus right", node{token{operator, "-", 0, 0}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "5", 0, 0}, nil, nil, nil, nil}, nil, nil}, -2},
+	{"Operator * token returns left multiplied by right", node{token{operator, "*", 0, 0}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "5", 0, 0}, nil, nil, nil, nil}, nil, nil}, 15},
+	{"Operator / token returns left divided right", node{token{operator, "/", 0, 0}, &node{token{literal, "10", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "5", 0, 0}, nil, nil, nil, nil}, nil, nil}, 2},
+	{"Division of 2 ints rounds down.", node{token{operator, "/", 0, 0}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "2", 0, 0}, nil, nil, nil, nil}, nil, nil}, 1},
+	{"Operator % token returns remainder of left divided by right", node{token{operator, "%", 0, 0}, &node{token{literal, "7", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, nil, nil}, 1},
+	{"Operator ^ token returns left to the power of right", node{token{operator, "^", 0, 0}, &node{token{literal, "2", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "10", 0, 0}, nil, nil, nil, nil}, nil, nil}, 1024},
+	{"Operator ** token returns left to the power of right", node{token{operator, "**", 0, 0}, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, &node{token{literal, "0", 0, 0}, nil, nil, nil, nil}, nil, nil}, 1},
+	{"Function call returns the result of the function", node{token{identifier, "max", 0, 0}, nil, nil, []*node{{token{literal, "4", 0, 0}, nil, nil, nil, nil}, {token{literal, "5", 0, 0}, nil, nil, nil, nil}}, nil}, 5},
+	{"Unary operator - token returns negated right", node{token{unaryOperator, "-", 0, 0}, nil, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, nil, nil}, -3},
+	{"Unary operator + token returns right", node{token{unaryOperator, "+", 0, 0}, nil, &node{token{literal, "3", 0, 0}, nil, nil, nil, nil}, nil, nil}, 3},
 }
 
 func TestWalkWithValidAst(t *testing.T) {
 	for _, tc := range validWalkTestCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := walk(&tc.root)
+			res, err := walk(&tc.root, defaultOptions())
 			if err != nil {
 				t.Fatalf("Expected error to be nil but got error with message %s\n", err.Error())
 			}
+			if res.Value.Int() != tc.expectedResult {
+				t.Fatalf("Expected result to be %d but found %d\n", tc.expectedResult, res.Value.Int())
+			}
 		})
 	}
 }
@@ -39,7 +57,7 @@ func TestWalkWithValidAst(t *testing.T) {
 func TestWalkWithInvalidAst(t *testing.T) {
 	for _, tc := range invalidWalkTestCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := walk(&tc.root)
+			_, err := walk(&tc.root, defaultOptions())
 			if err == nil {
 				t.Fatalf("Expected error to not be nil\n")
 			}
@@ -0,0 +1,708 @@
+package dice
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Outcome is one value an expression can evaluate to and the probability of it
+type Outcome struct {
+	Value       Value
+	Probability float64
+}
+
+// Distribution is the exact probability of every value an expression can evaluate to.
+// Outcomes are sorted with booleans, false then true, before integers in ascending order.
+type Distribution []Outcome
+
+// Probability returns the probability of the expression evaluating to v
+func (distribution Distribution) Probability(v Value) float64 {
+	for _, outcome := range distribution {
+		if outcome.Value == v {
+			return outcome.Probability
+		}
+	}
+	return 0
+}
+
+// negligibleProbability is the probability below which explosion chains are no longer followed
+const negligibleProbability = 1e-15
+
+// analysisCheckInterval is how many steps exact analysis takes between checks of the context
+const analysisCheckInterval = 1 << 14
+
+// outcomes maps each value an expression can evaluate to to its probability while the distribution is built
+type outcomes map[Value]float64
+
+// Distribution analyses the expression instead of rolling it and returns the exact probability of every value it can
+// evaluate to. Explosion chains longer than the explosion limit and rerolls past the reroll limit make Roll return an
+// error, so they are left out and the remaining outcomes are scaled back up to a total probability of 1. Explosion
+// chains less likely than 1e-15 are left out the same way.
+// Expressions where some outcomes return an error, e.g. a division by a roll that can be 0, return that error.
+func (expression *Expression) Distribution(opts ...Option) (Distribution, error) {
+	dist, err := analyze(expression.root, applyOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	return dist.sorted(), nil
+}
+
+func (dist outcomes) sorted() Distribution {
+	distribution := make(Distribution, 0, len(dist))
+	for v, p := range dist {
+		if p > 0 {
+			distribution = append(distribution, Outcome{v, p})
+		}
+	}
+
+	slices.SortFunc(distribution, func(a, b Outcome) int {
+		if a.Value.IsBool() != b.Value.IsBool() {
+			if a.Value.IsBool() {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Value.Int(), b.Value.Int())
+	})
+	return distribution
+}
+
+// analyze walks the tree like walk, but combines the distributions of the children instead of rolled values
+func analyze(root *node, opts options) (outcomes, error) {
+	if err := opts.ctx.Err(); err != nil {
+		return nil, err
+	}
+	dist, err := analyzeNode(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutcomes(len(dist), opts); err != nil {
+		return nil, err
+	}
+	return dist, nil
+}
+
+func analyzeNode(root *node, opts options) (outcomes, error) {
+	switch root.token.kind {
+	case eof:
+		return outcomes{intValue(0): 1}, nil
+	case unaryOperator:
+		if root.right == nil {
+			return nil, fmt.Errorf("root node is a unary operator node with a nil right.")
+		}
+		rhs, err := analyze(root.right, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		dist := outcomes{}
+		for v, p := range rhs {
+			result, err := unary(root.token.value, v)
+			if err != nil {
+				return nil, err
+			}
+			dist[result] += p
+		}
+		return dist, nil
+	case operator:
+		if root.left == nil || root.right == nil {
+			return nil, fmt.Errorf("root node is an operator node with a nil right or left.")
+		}
+		lhs, err := analyze(root.left, opts)
+		if err != nil {
+			return nil, err
+		}
+		if root.token.value == "?" {
+			return analyzeConditional(root, lhs, opts)
+		}
+		return analyzeBinary(root, lhs, opts)
+	case identifier:
+		return analyzeCall(root, opts)
+	case dice:
+		term := root.term
+		if term == nil {
+			parsed, err := parseDiceTerm(root.token.value)
+			if err != nil {
+				return nil, err
+			}
+			term = &parsed
+		}
+
+		termDist, err := term.distribution(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		dist := outcomes{}
+		for v, p := range termDist {
+			dist[intValue(v)] += p
+		}
+		return dist, nil
+	default:
+		n, err := root.token.evaluate(opts)
+		if err != nil {
+			return nil, err
+		}
+		return outcomes{intValue(n): 1}, nil
+	}
+}
+
+func analyzeBinary(root *node, lhs outcomes, opts options) (outcomes, error) {
+	dist := outcomes{}
+	var rhs outcomes
+	step := 0
+	for l, pl := range lhs {
+		// && and || don't evaluate their right side when the left side decides the result
+		if root.token.value == "&&" || root.token.value == "||" {
+			if !l.IsBool() {
+				return nil, errorf(ErrType, "Operator %s needs boolean operands but found %s.", root.token.value, l)
+			}
+			if l.Bool() == (root.token.value == "||") {
+				dist[l] += pl
+				continue
+			}
+		}
+
+		if rhs == nil {
+			var err error
+			rhs, err = analyze(root.right, opts)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkCombinations(len(lhs), len(rhs), opts); err != nil {
+				return nil, err
+			}
+		}
+
+		for r, pr := range rhs {
+			if err := checkContext(&step, opts); err != nil {
+				return nil, err
+			}
+			v, err := binary(root.token.value, l, r)
+			if err != nil {
+				return nil, err
+			}
+			dist[v] += pl * pr
+			// stop as soon as there are too many outcomes rather than after combining every pair
+			if err := checkOutcomes(len(dist), opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return dist, nil
+}
+
+func analyzeConditional(root *node, cond outcomes, opts options) (outcomes, error) {
+	branches := root.right
+	if branches.token.value != ":" || branches.left == nil || branches.right == nil {
+		return nil, fmt.Errorf("Conditional node must have a : node with both branches on its right.")
+	}
+
+	dist := outcomes{}
+	for c, pc := range cond {
+		if !c.IsBool() {
+			return nil, conditionTypeError(root.left, c)
+		}
+
+		branch := branches.right
+		if c.Bool() {
+			branch = branches.left
+		}
+		branchDist, err := analyze(branch, opts)
+		if err != nil {
+			return nil, err
+		}
+		for v, p := range branchDist {
+			dist[v] += pc * p
+		}
+	}
+	return dist, nil
+}
+
+func analyzeCall(root *node, opts options) (outcomes, error) {
+	if err := checkArguments(root.token.value, root.args); err != nil {
+		return nil, err
+	}
+	fn := functions[root.token.value]
+
+	var dist map[int]float64
+	if arg := root.args[0]; fn.divide != nil {
+		if arg.left == nil || arg.right == nil {
+			return nil, fmt.Errorf("root node is an operator node with a nil right or left.")
+		}
+		lhs, err := analyzeInt(arg.left, opts)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := analyzeInt(arg.right, opts)
+		if err != nil {
+			return nil, err
+		}
+		if dist, err = combine(lhs, rhs, fn.divide, opts); err != nil {
+			return nil, err
+		}
+	} else {
+		first, err := analyzeInt(root.args[0], opts)
+		if err != nil {
+			return nil, err
+		}
+		dist = map[int]float64{}
+		for v, p := range first {
+			n, err := fn.call([]int{v})
+			if err != nil {
+				return nil, err
+			}
+			dist[n] += p
+		}
+
+		// functions fold over their arguments, so combine the arguments one at a time with the result so far
+		for _, arg := range root.args[1:] {
+			argDist, err := analyzeInt(arg, opts)
+			if err != nil {
+				return nil, err
+			}
+			dist, err = combine(dist, argDist, func(l int, r int) (int, error) {
+				return fn.call([]int{l, r})
+			}, opts)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result := outcomes{}
+	for v, p := range dist {
+		result[intValue(v)] += p
+	}
+	return result, nil
+}
+
+// analyzeInt analyses the tree and returns an error when any of its outcomes is a boolean
+func analyzeInt(root *node, opts options) (map[int]float64, error) {
+	dist, err := analyze(root, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ints := make(map[int]float64, len(dist))
+	for v, p := range dist {
+		if v.IsBool() {
+			return nil, errorf(ErrType, "Expected an integer but found %s.", v)
+		}
+		ints[v.Int()] = p
+	}
+	return ints, nil
+}
+
+// contribution is what a single kept die showing v adds to the term: its value, or +1, -1 or 0 in a success pool
+func (term diceTerm) contribution(v int) int {
+	if !term.countSuccesses {
+		return v
+	}
+	if term.successAt.matches(v) {
+		return 1
+	}
+	if term.countFailures && term.failureAt.matches(v) {
+		return -1
+	}
+	return 0
+}
+
+// faceDistribution returns the distribution of a single rolled face after the term's reroll modifier
+func (term diceTerm) faceDistribution(opts options) (map[int]float64, error) {
+	if err := term.validate(); err != nil {
+		return nil, err
+	}
+	if err := term.checkLimits(opts); err != nil {
+		return nil, err
+	}
+	if err := checkOutcomes(term.faces, opts); err != nil {
+		return nil, err
+	}
+
+	base := map[int]float64{}
+	// count the matching faces rather than adding up their probabilities, which can fall just short of 1
+	matchingFaces := 0
+	for i := range term.faces {
+		v := term.face(i)
+		base[v] += 1 / float64(term.faces)
+		if term.reroll != rerollNone && term.rerollAt.matches(v) {
+			matchingFaces++
+		}
+	}
+	matching := float64(matchingFaces) / float64(term.faces)
+
+	switch term.reroll {
+	case rerollAlways:
+		// every reroll is independent, so a die that is rerolled until it stops matching shows each other face
+		// with its base probability scaled up by the probability of not matching
+		if matchingFaces == term.faces {
+			return nil, errorf(ErrTooManyRerolls, "Every face of the die matches its reroll compare point.")
+		}
+		dist := map[int]float64{}
+		for v, p := range base {
+			if !term.rerollAt.matches(v) {
+				dist[v] = p / (1 - matching)
+			}
+		}
+		return dist, nil
+	case rerollOnce:
+		dist := map[int]float64{}
+		for v, p := range base {
+			dist[v] += matching * p
+			if !term.rerollAt.matches(v) {
+				dist[v] += p
+			}
+		}
+		return dist, nil
+	default:
+		return base, nil
+	}
+}
+
+// chainDistribution returns the distribution of what a single die adds to the term, including every die its
+// explosions rolled. Compounded dice are a single die whose total is passed through contribution, other explosions
+// add a die per roll and, when penetrating, take 1 off every extra die.
+func (term diceTerm) chainDistribution(opts options) (map[int]float64, error) {
+	faces, err := term.faceDistribution(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dist := map[int]float64{}
+	// chains that exploded on their last roll, by what they added so far
+	exploding := map[int]float64{0: 1}
+	for rollIndex := 0; len(exploding) > 0; rollIndex++ {
+		next := map[int]float64{}
+		for acc, pAcc := range exploding {
+			for face, pFace := range faces {
+				amount := face
+				if term.explode == explodePenetrate && rollIndex > 0 {
+					if amount, err = add(amount, -1); err != nil {
+						return nil, err
+					}
+				}
+				if term.explode != explodeCompound {
+					amount = term.contribution(amount)
+				}
+				total, err := add(acc, amount)
+				if err != nil {
+					return nil, err
+				}
+
+				if term.explode == explodeNone || !term.explodeAt.matches(face) {
+					dist[total] += pAcc * pFace
+				} else if rollIndex < opts.maxExplosions && pAcc*pFace >= negligibleProbability {
+					next[total] += pAcc * pFace
+				}
+				// chains that explode more than maxExplosions times make the roll return an error and are left out,
+				// like chains that are too unlikely to change the distribution
+			}
+		}
+		exploding = next
+	}
+
+	if term.explode == explodeCompound {
+		contributions := map[int]float64{}
+		for v, p := range dist {
+			contributions[term.contribution(v)] += p
+		}
+		dist = contributions
+	}
+	return normalize(dist, ErrTooManyExplosions, "Every roll of the die explodes more than the limit of %d times in a row.", opts.maxExplosions)
+}
+
+// distribution returns the exact distribution of the term's total
+func (term diceTerm) distribution(opts options) (map[int]float64, error) {
+	if err := term.validate(); err != nil {
+		return nil, err
+	}
+	if err := term.checkLimits(opts); err != nil {
+		return nil, err
+	}
+
+	if term.selection == selectAll {
+		die, err := term.chainDistribution(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		// equally likely faces in a row sum with a running window, so the work only grows with the number of outcomes
+		if lo, hi, ok := uniformRange(die); ok {
+			return sumUniform(lo, hi, term.count, opts)
+		}
+
+		// sum count dice by squaring, so huge counts of dice with few faces only take a few steps
+		dist := map[int]float64{0: 1}
+		for n := term.count; n > 0; n >>= 1 {
+			if n&1 == 1 {
+				if dist, err = convolve(dist, die, opts); err != nil {
+					return nil, err
+				}
+			}
+			if n > 1 {
+				if die, err = convolve(die, die, opts); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return dist, nil
+	}
+
+	// keeping and dropping picks dice by value, so every die has to be a single value rather than a chain of dice
+	if term.explode != explodeNone && term.explode != explodeCompound {
+		return nil, errorf(errors.ErrUnsupported, "Exact analysis of keep or drop modifiers is only supported with compounding explosions.")
+	}
+
+	// the dice are sorted by value, so take the distribution of the value of a single die and count successes after
+	values := term
+	values.countSuccesses = false
+	values.countFailures = false
+	die, err := values.chainDistribution(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := term.selectCount
+	if term.selection == dropHighest || term.selection == dropLowest {
+		kept = term.count - term.selectCount
+	}
+
+	order := make([]int, 0, len(die))
+	for v := range die {
+		order = append(order, v)
+	}
+	slices.Sort(order)
+	if term.selection == keepHighest || term.selection == dropLowest {
+		slices.Reverse(order)
+	}
+
+	return keepDistribution(order, die, term.count, kept, term.contribution, opts)
+}
+
+// keepDistribution returns the distribution of the sum of contribution over the first kept of count independent dice,
+// after sorting the dice by the order of values. die is the distribution of a single die.
+//
+// The dice are placed value by value in that order. For every value, any number c of the dice that haven't been placed
+// yet can show it, with probability C(remaining, c) * p^c, and the ones among the first kept dice add to the sum.
+func keepDistribution(values []int, die map[int]float64, count int, kept int, contribution func(int) int, opts options) (map[int]float64, error) {
+	// placed[j] is the distribution of the kept sum after placing j dice
+	placed := make([]map[int]float64, count+1)
+	placed[0] = map[int]float64{0: 1}
+	step := 0
+	// every value places up to count dice on every state so far, and the work of all values shares one budget
+	budget := opts.maxCombinations
+	for _, v := range values {
+		logP := math.Log(die[v])
+		states := 0
+		for _, sums := range placed {
+			states += len(sums)
+		}
+		if budget -= states * (count + 1); budget < 0 {
+			return nil, errorf(ErrLimitExceeded, "Exact analysis of keep or drop modifiers combines more than the limit of %d outcomes.", opts.maxCombinations)
+		}
+
+		next := make([]map[int]float64, count+1)
+		for j, sums := range placed {
+			if len(sums) == 0 {
+				continue
+			}
+			for c := 0; j+c <= count; c++ {
+				// placing c more dice with value v weighs and shifts every sum so far the same way
+				factor := 1.0
+				if c > 0 {
+					factor = math.Exp(logChoose(count-j, c) + float64(c)*logP)
+				}
+				added, err := multiply(min(c, max(0, kept-j)), contribution(v))
+				if err != nil {
+					return nil, err
+				}
+
+				if next[j+c] == nil {
+					next[j+c] = map[int]float64{}
+				}
+				for sum, pSum := range sums {
+					if err := checkContext(&step, opts); err != nil {
+						return nil, err
+					}
+					total, err := add(sum, added)
+					if err != nil {
+						return nil, err
+					}
+					next[j+c][total] += pSum * factor
+				}
+			}
+		}
+		placed = next
+	}
+
+	if placed[count] == nil {
+		return map[int]float64{0: 1}, nil
+	}
+	return placed[count], nil
+}
+
+// checkContext counts a step of the analysis and returns the error of the context once it is done, checking it every
+// analysisCheckInterval steps
+func checkContext(step *int, opts options) error {
+	*step++
+	if *step%analysisCheckInterval == 0 {
+		return opts.ctx.Err()
+	}
+	return nil
+}
+
+// checkOutcomes returns an error when a distribution has more distinct values than the analysis allows
+func checkOutcomes(n int, opts options) error {
+	if n > opts.maxOutcomes {
+		return errorf(ErrLimitExceeded, "Exact analysis has more than the limit of %d outcomes.", opts.maxOutcomes)
+	}
+	return nil
+}
+
+// checkCombinations returns an error when combining a outcomes with b outcomes is more work than the analysis allows
+func checkCombinations(a int, b int, opts options) error {
+	if b > 0 && a > opts.maxCombinations/b {
+		return errorf(ErrLimitExceeded, "Exact analysis combines more than the limit of %d outcomes in a single step.", opts.maxCombinations)
+	}
+	return nil
+}
+
+func logChoose(n int, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// uniformRange returns the lowest and highest value of a distribution when every value between them is equally likely
+func uniformRange(die map[int]float64) (int, int, bool) {
+	lo, hi := math.MaxInt, math.MinInt
+	var p float64
+	for v, pv := range die {
+		lo, hi, p = min(lo, v), max(hi, v), pv
+	}
+	if len(die) == 0 || hi-lo != len(die)-1 {
+		return 0, 0, false
+	}
+	for _, pv := range die {
+		if math.Abs(pv-p) > 1e-12*p {
+			return 0, 0, false
+		}
+	}
+	return lo, hi, true
+}
+
+// sumUniform returns the distribution of the sum of count dice with equally likely faces from lo to hi. Every die
+// slides a window of faces over the sums so far. The window runs from the low end up to the middle and from the high
+// end down to it, so it only ever subtracts sums smaller than the ones it keeps and the tails stay exact.
+func sumUniform(lo int, hi int, count int, opts options) (map[int]float64, error) {
+	faces := hi - lo + 1
+	spread, err := multiply(count, faces-1)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutcomes(spread+1, opts); err != nil {
+		return nil, err
+	}
+	lowest, err := multiply(count, lo)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := add(lowest, spread); err != nil {
+		return nil, err
+	}
+
+	p := 1 / float64(faces)
+	sums := []float64{1}
+	for range count {
+		if err := opts.ctx.Err(); err != nil {
+			return nil, err
+		}
+		next := make([]float64, len(sums)+faces-1)
+		mid := (len(next) - 1) / 2
+		window := 0.0
+		for i := 0; i <= mid; i++ {
+			if i < len(sums) {
+				window += sums[i]
+			}
+			if i >= faces {
+				window -= sums[i-faces]
+			}
+			next[i] = max(window, 0) * p
+		}
+		window = 0
+		for i := len(next) - 1; i > mid; i-- {
+			if j := i - faces + 1; j >= 0 && j < len(sums) {
+				window += sums[j]
+			}
+			if i+1 < len(sums) {
+				window -= sums[i+1]
+			}
+			next[i] = max(window, 0) * p
+		}
+		sums = next
+	}
+
+	dist := make(map[int]float64, len(sums))
+	for i, ps := range sums {
+		if ps > 0 {
+			dist[lowest+i] = ps
+		}
+	}
+	return dist, nil
+}
+
+// convolve returns the distribution of the sum of two independent distributions
+func convolve(a map[int]float64, b map[int]float64, opts options) (map[int]float64, error) {
+	return combine(a, b, add, opts)
+}
+
+// combine returns the distribution of fn of a value of a and a value of b, for two independent distributions. It
+// stops as soon as the result has more outcomes than the analysis allows.
+func combine(a map[int]float64, b map[int]float64, fn func(l int, r int) (int, error), opts options) (map[int]float64, error) {
+	if err := opts.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkCombinations(len(a), len(b), opts); err != nil {
+		return nil, err
+	}
+
+	dist := make(map[int]float64, len(a)+len(b))
+	step := 0
+	for va, pa := range a {
+		for vb, pb := range b {
+			if err := checkContext(&step, opts); err != nil {
+				return nil, err
+			}
+			v, err := fn(va, vb)
+			if err != nil {
+				return nil, err
+			}
+			dist[v] += pa * pb
+			if err := checkOutcomes(len(dist), opts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return dist, nil
+}
+
+// normalize scales the distribution back up to a total probability of 1 after outcomes were left out
+func normalize(dist map[int]float64, sentinel error, emptyFormat string, args ...any) (map[int]float64, error) {
+	total := 0.0
+	for _, p := range dist {
+		total += p
+	}
+	if total == 0 {
+		return nil, errorf(sentinel, emptyFormat, args...)
+	}
+
+	for v := range dist {
+		dist[v] /= total
+	}
+	return dist, nil
+}
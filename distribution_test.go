@@ -0,0 +1,222 @@
+package dice
+
+import (
+	"math"
+	"testing"
+)
+
+type distributionTestCase struct {
+	input    string
+	value    Value
+	expected float64
+}
+
+var validDistributionTestCases = []distributionTestCase{
+	{"1d6", intValue(1), 1.0 / 6},
+	{"1d6", intValue(7), 0},
+	{"2d6", intValue(7), 6.0 / 36},
+	{"2d6+1", intValue(13), 1.0 / 36},
+	{"3d6", intValue(10), 27.0 / 216},
+	{"2d6r1", intValue(12), 1.0 / 25},
+	{"100d100", intValue(5050), 0.0013799706277169027},
+	{"10000d2", intValue(15000), 0.007978646139266547},
+	{"4d6kh3", intValue(18), 21.0 / 1296},
+	{"4d6kh3", intValue(3), 1.0 / 1296},
+	{"2d20kl1", intValue(20), 1.0 / 400},
+	{"2d20dh1", intValue(1), 39.0 / 400},
+	{"1d6/2", intValue(0), 1.0 / 6},
+	{"1d6/2", intValue(1), 2.0 / 6},
+	{"-1d6/2", intValue(-3), 1.0 / 6},
+	{"1d6%3", intValue(0), 2.0 / 6},
+	{"1d6^2", intValue(36), 1.0 / 6},
+	{"4dF", intValue(4), 1.0 / 81},
+	{"d{1,1,2}", intValue(1), 2.0 / 3},
+	{"d6!", intValue(6), 0},
+	{"d6!", intValue(7), 1.0 / 36},
+	{"d6!!", intValue(13), 1.0 / 216},
+	{"d6!p", intValue(6), 1.0 / 36},
+	{"d6r1", intValue(1), 0},
+	{"d6r1", intValue(2), 1.0 / 5},
+	{"d6ro1", intValue(1), 1.0 / 36},
+	{"d6ro1", intValue(2), 7.0 / 36},
+	{"3d6>=5", intValue(3), 1.0 / 27},
+	{"2d6>=5f1", intValue(0), 2*(2.0/6)*(1.0/6) + (3.0/6)*(3.0/6)},
+	{"3d6kh2>=5", intValue(2), 7.0 / 27},
+	{"2d6!6>=5", intValue(0), (4.0 / 6) * (4.0 / 6)},
+	{"2d6>=5f1kl1", intValue(-1), 11.0 / 36},
+	{"2d6>=5f1kh1", intValue(-1), 1.0 / 36},
+	{"1d20 >= 11", boolValue(true), 0.5},
+	{"1d6 == 1d6", boolValue(true), 1.0 / 6},
+	{"1d2 == 1 && 1d2 == 1", boolValue(true), 0.25},
+	{"1d2 == 1 || 1d2 == 1", boolValue(true), 0.75},
+	{"1d20 >= 11 ? 1d6 : 0", intValue(0), 0.5},
+	{"1d20 >= 11 ? 1d6 : 0", intValue(1), 0.5 / 6},
+	{"max(1d6, 1d6)", intValue(6), 11.0 / 36},
+	{"min(1d6, 1d6, 1d6)", intValue(6), 1.0 / 216},
+	{"min(1d200, 1d200, 1d200)", intValue(200), 1.0 / 8_000_000},
+	{"max(1d6, 1d6 > 3 ? 10 : 0)", intValue(10), 0.5},
+	{"floor(1d6/4)", intValue(1), 3.0 / 6},
+	{"ceil(1d6/4)", intValue(2), 2.0 / 6},
+	{"round(1d6/4)", intValue(1), 4.0 / 6},
+}
+
+var invalidDistributionTestCases = []string{
+	"1d6/(1d2-1)",
+	"1d6%(1d2-1)",
+	"2d6!kh1",
+	"d1!",
+	"d1r1",
+	"1d2 == 1 ? 1 : 1 + (1 < 2)",
+}
+
+func TestDistributionOfValidExpressions(t *testing.T) {
+	for _, tc := range validDistributionTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expression, err := Compile([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			distribution, err := expression.Distribution()
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			if p := distribution.Probability(tc.value); math.Abs(p-tc.expected) > 1e-9 {
+				t.Fatalf("Expected probability of %s to be %f but found %f.\n", tc.value, tc.expected, p)
+			}
+
+			total := 0.0
+			for _, outcome := range distribution {
+				total += outcome.Probability
+			}
+			if math.Abs(total-1) > 1e-9 {
+				t.Fatalf("Expected probabilities to add up to 1 but found %f.\n", total)
+			}
+		})
+	}
+}
+
+func TestDistributionOfInvalidExpressions(t *testing.T) {
+	for _, input := range invalidDistributionTestCases {
+		t.Run(input, func(t *testing.T) {
+			expression, err := Compile([]byte(input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			if _, err := expression.Distribution(); err == nil {
+				t.Fatalf("Expected error but found none.\n")
+			}
+		})
+	}
+}
+
+func TestDistributionIsSorted(t *testing.T) {
+	expression, err := Compile([]byte("1d2 == 1 ? 1d3 : 1 < 2"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	distribution, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	expected := []Value{boolValue(true), intValue(1), intValue(2), intValue(3)}
+	if len(distribution) != len(expected) {
+		t.Fatalf("Expected %d outcomes but found %d.\n", len(expected), len(distribution))
+	}
+	for i, outcome := range distribution {
+		if outcome.Value != expected[i] {
+			t.Fatalf("Expected outcome %d to be %s but found %s.\n", i, expected[i], outcome.Value)
+		}
+	}
+}
+
+func TestDistributionIsSortedAtTheLimitsOfInt(t *testing.T) {
+	expression, err := Compile([]byte("1d2 == 1 ? -9000000000000000000 : 9000000000000000000"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	distribution, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	expected := []Value{intValue(-9000000000000000000), intValue(9000000000000000000)}
+	if len(distribution) != len(expected) {
+		t.Fatalf("Expected %d outcomes but found %d.\n", len(expected), len(distribution))
+	}
+	for i, outcome := range distribution {
+		if outcome.Value != expected[i] {
+			t.Fatalf("Expected outcome %d to be %s but found %s.\n", i, expected[i], outcome.Value)
+		}
+	}
+}
+
+func TestDistributionKeepMatchesEnumeration(t *testing.T) {
+	expression, err := Compile([]byte("3d4kl2"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	distribution, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	expected := map[int]float64{}
+	for a := 1; a <= 4; a++ {
+		for b := 1; b <= 4; b++ {
+			for c := 1; c <= 4; c++ {
+				expected[a+b+c-max(a, b, c)] += 1.0 / 64
+			}
+		}
+	}
+
+	for v, p := range expected {
+		if found := distribution.Probability(intValue(v)); math.Abs(found-p) > 1e-9 {
+			t.Fatalf("Expected probability of %d to be %f but found %f.\n", v, p, found)
+		}
+	}
+}
+
+func TestDistributionKeepsTheTailsOfLargeSums(t *testing.T) {
+	expression, err := Compile([]byte("100d100"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	distribution, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	for _, v := range []int{100, 10000} {
+		if p := distribution.Probability(intValue(v)); math.Abs(p/1e-200-1) > 1e-9 {
+			t.Fatalf("Expected probability of %d to be %g but found %g.\n", v, 1e-200, p)
+		}
+	}
+}
+
+func TestDistributionWithMaxExplosions(t *testing.T) {
+	expression, err := Compile([]byte("d2!"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	distribution, err := expression.Distribution(WithMaxExplosions(1))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	// 1 with probability 1/2 and 3 with probability 1/4, while 2, 2 explodes past the limit and is left out
+	if p := distribution.Probability(intValue(1)); math.Abs(p-2.0/3) > 1e-9 {
+		t.Fatalf("Expected probability of 1 to be %f but found %f.\n", 2.0/3, p)
+	}
+	if p := distribution.Probability(intValue(3)); math.Abs(p-1.0/3) > 1e-9 {
+		t.Fatalf("Expected probability of 3 to be %f but found %f.\n", 1.0/3, p)
+	}
+}
@@ -0,0 +1,155 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Errors returned while parsing, evaluating or analysing an expression wrap one of these, so callers can tell them
+// apart with errors.Is. Exact analysis of modifiers it can't handle and negative exponents wrap errors.ErrUnsupported.
+var (
+	// ErrSyntax is wrapped by every *SyntaxError
+	ErrSyntax = errors.New("syntax error")
+	// ErrUnknownOperator is an operator that doesn't exist, like a single &, or that can't be used where it was found
+	ErrUnknownOperator = errors.New("unknown operator")
+	// ErrUnknownFunction is a call to a function that doesn't exist
+	ErrUnknownFunction = errors.New("unknown function")
+	// ErrType is a boolean where an integer is needed or the other way around
+	ErrType = errors.New("type error")
+	// ErrDivisionByZero is a division or remainder by zero
+	ErrDivisionByZero = errors.New("division by zero")
+	// ErrOverflow is an integer that doesn't fit in an int, in the expression or in the result of an operator
+	ErrOverflow = errors.New("integer overflow")
+	// ErrLimitExceeded is a limit, like the number of explosions of a die or the outcomes of exact analysis, being exceeded
+	ErrLimitExceeded = errors.New("limit exceeded")
+	// ErrInvalidArgument is an argument to a function of this package that it can't work with, like 0 simulation runs
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrParserReused is a parser parsing again without a call to Reset in between
+	ErrParserReused = errors.New("parser reused without reset")
+)
+
+// Errors for each of the limits set by options wrap one of these. They all wrap ErrLimitExceeded too.
+var (
+	// ErrTooManyDice is an expression rolling more dice than WithMaxDice allows
+	ErrTooManyDice = fmt.Errorf("too many dice: %w", ErrLimitExceeded)
+	// ErrTooManyFaces is a die with more faces than WithMaxFaces allows
+	ErrTooManyFaces = fmt.Errorf("too many faces: %w", ErrLimitExceeded)
+	// ErrTooDeep is an expression nested deeper than WithMaxDepth allows
+	ErrTooDeep = fmt.Errorf("expression too deep: %w", ErrLimitExceeded)
+	// ErrTooManyTokens is an expression with more tokens than WithMaxTokens allows
+	ErrTooManyTokens = fmt.Errorf("too many tokens: %w", ErrLimitExceeded)
+	// ErrTooManyExplosions is a die exploding more times in a row than WithMaxExplosions allows
+	ErrTooManyExplosions = fmt.Errorf("too many explosions: %w", ErrLimitExceeded)
+	// ErrTooManyRerolls is a die being rerolled more times than WithMaxRerolls allows
+	ErrTooManyRerolls = fmt.Errorf("too many rerolls: %w", ErrLimitExceeded)
+)
+
+// sentinelError is an error with its own message that matches a sentinel error with errors.Is
+type sentinelError struct {
+	message  string
+	sentinel error
+}
+
+func (err *sentinelError) Error() string {
+	return err.message
+}
+
+func (err *sentinelError) Unwrap() error {
+	return err.sentinel
+}
+
+// errorf returns an error with the formatted message that wraps sentinel
+func errorf(sentinel error, format string, args ...any) error {
+	return &sentinelError{fmt.Sprintf(format, args...), sentinel}
+}
+
+// SyntaxError is a mistake in the text of an expression found while scanning or parsing it
+type SyntaxError struct {
+	// Start and End are the byte offsets of the problem in Input, End is just past its last byte
+	Start int
+	End   int
+	// Expected describes what should have been at Start, e.g. "EOF or operator". It is empty when any token would do.
+	Expected string
+	// Found is the kind of what was at Start, e.g. "literal", or "byte" when it couldn't be scanned as a token
+	Found   string
+	Message string
+	Input   string
+	// Err is the error the problem was found with, e.g. one wrapping ErrUnknownOperator. It can be nil.
+	Err error
+}
+
+// Error returns the message followed by the offset of the problem,
+// e.g. Expected EOF or operator. Found literal 2 at offset 4.
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d.", strings.TrimSuffix(err.Message, "."), err.Start)
+}
+
+// Unwrap returns ErrSyntax and Err, so errors.Is matches both
+func (err *SyntaxError) Unwrap() []error {
+	if err.Err == nil {
+		return []error{ErrSyntax}
+	}
+	return []error{ErrSyntax, err.Err}
+}
+
+// Snippet renders the line of the input with the problem and a caret under every byte of it, e.g.
+//
+//	1d6 + * 2
+//	      ^
+func (err *SyntaxError) Snippet() string {
+	start := min(max(err.Start, 0), len(err.Input))
+	end := min(max(err.End, start), len(err.Input))
+
+	lineStart := strings.LastIndexByte(err.Input[:start], '\n') + 1
+	lineEnd := len(err.Input)
+	if i := strings.IndexByte(err.Input[start:], '\n'); i >= 0 {
+		lineEnd = start + i
+	}
+	end = min(end, lineEnd)
+
+	// keep tabs in the padding so the caret lines up with the input however tabs are displayed
+	var padding strings.Builder
+	for _, r := range err.Input[lineStart:start] {
+		if r == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteByte(' ')
+		}
+	}
+
+	carets := max(1, utf8.RuneCountInString(err.Input[start:end]))
+	return err.Input[lineStart:lineEnd] + "\n" + padding.String() + strings.Repeat("^", carets)
+}
+
+// SyntaxErrors are the syntax errors CompileWithRecovery found in an expression, in the order they appear in it
+type SyntaxErrors []*SyntaxError
+
+// Error returns the messages of the errors, one per line
+func (errs SyntaxErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As match any of them
+func (errs SyntaxErrors) Unwrap() []error {
+	unwrapped := make([]error, len(errs))
+	for i, err := range errs {
+		unwrapped[i] = err
+	}
+	return unwrapped
+}
+
+// newSyntaxError returns a SyntaxError for the bytes of input from start to end
+func newSyntaxError(input []byte, start int, end int, expected string, found string, format string, args ...any) *SyntaxError {
+	return &SyntaxError{start, end, expected, found, fmt.Sprintf(format, args...), string(input), nil}
+}
+
+// wrapSyntaxError returns a SyntaxError for the bytes of input from start to end with the message of err that wraps err
+func wrapSyntaxError(input []byte, start int, end int, expected string, found string, err error) *SyntaxError {
+	return &SyntaxError{start, end, expected, found, err.Error(), string(input), err}
+}
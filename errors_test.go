@@ -0,0 +1,315 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+type syntaxErrorTestCase struct {
+	input    string
+	start    int
+	end      int
+	expected string
+	found    string
+	snippet  string
+}
+
+var syntaxErrorTestCases = []syntaxErrorTestCase{
+	{"1d6 + * 2", 6, 7, "dice, literal, function, ( or prefix operator", "operator", "1d6 + * 2\n      ^"},
+	{"1 2", 2, 3, "EOF or operator", "literal", "1 2\n  ^"},
+	{"(1 + 2", 6, 6, ")", "EOF", "(1 + 2\n      ^"},
+	{"1 + 1)", 5, 6, "EOF", "operator", "1 + 1)\n     ^"},
+	{"1d20 >= 10 ? 1 , 2", 15, 16, ":", "operator", "1d20 >= 10 ? 1 , 2\n               ^"},
+	{"max 1", 4, 5, "(", "literal", "max 1\n    ^"},
+	{"max(1 : 2)", 6, 7, ", or )", "operator", "max(1 : 2)\n      ^"},
+	{"sqrt(4)", 0, 4, "", "identifier", "sqrt(4)\n^^^^"},
+	{"2 + 4d6k3", 4, 9, "", "dice", "2 + 4d6k3\n    ^^^^^"},
+	{"1 + 2c", 5, 6, "whitespace, operator or EOF", "byte", "1 + 2c\n     ^"},
+	{"1 & 2", 2, 3, "&&", "operator", "1 & 2\n  ^"},
+	{"1 + $", 4, 5, "", "byte", "1 + $\n    ^"},
+	{"1 +\n\t2 3", 7, 8, "EOF or operator", "literal", "\t2 3\n\t  ^"},
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for _, tc := range syntaxErrorTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := Compile([]byte(tc.input))
+
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a *SyntaxError but found %v.\n", err)
+			}
+			if syntaxErr.Start != tc.start || syntaxErr.End != tc.end {
+				t.Fatalf("Expected span %d to %d but found %d to %d.\n", tc.start, tc.end, syntaxErr.Start, syntaxErr.End)
+			}
+			if syntaxErr.Expected != tc.expected || syntaxErr.Found != tc.found {
+				t.Fatalf("Expected %q and found %q but error had %q and %q.\n", tc.expected, tc.found, syntaxErr.Expected, syntaxErr.Found)
+			}
+			if syntaxErr.Snippet() != tc.snippet {
+				t.Fatalf("Expected snippet\n%s\nbut found\n%s\n", tc.snippet, syntaxErr.Snippet())
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorMessage(t *testing.T) {
+	_, err := Compile([]byte("1 2"))
+	expected := "Expected EOF or operator. Found literal 2 at offset 2."
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected error message %q but found %v.\n", expected, err)
+	}
+}
+
+type sentinelTestCase struct {
+	input    string
+	opts     []Option
+	sentinel error
+}
+
+var compileSentinelTestCases = []sentinelTestCase{
+	{"1 +* 2", nil, ErrSyntax},
+	{"1 & 2", nil, ErrSyntax},
+	{"1 & 2", nil, ErrUnknownOperator},
+	{"1 ! 2", nil, ErrUnknownOperator},
+	{"sqrt(4)", nil, ErrSyntax},
+	{"sqrt(4)", nil, ErrUnknownFunction},
+	{"abs(1, 2)", nil, ErrSyntax},
+	{"floor(7/2+1)", nil, ErrSyntax},
+	{"1d0", nil, ErrSyntax},
+	{"2d6kh3", nil, ErrSyntax},
+	{"99999999999999999999d6", nil, ErrOverflow},
+	{strings.Repeat("(", 101) + "1" + strings.Repeat(")", 101), nil, ErrTooDeep},
+	{strings.Repeat("(", 10_000), []Option{WithMaxTokens(100_000)}, ErrTooDeep},
+	{strings.Repeat("(", 10_000), nil, ErrTooManyTokens},
+	{strings.Repeat("-", 11) + "1", []Option{WithMaxDepth(10)}, ErrTooDeep},
+	{strings.Repeat("1+", 500) + "1", nil, ErrTooManyTokens},
+	{"1d6 + 1d6", []Option{WithMaxTokens(2)}, ErrTooManyTokens},
+	{"1d6 + 1d6", []Option{WithMaxTokens(2)}, ErrLimitExceeded},
+}
+
+var rollSentinelTestCases = []sentinelTestCase{
+	{"99999999999999999999", nil, ErrOverflow},
+	{"1/0", nil, ErrDivisionByZero},
+	{"1%(2-2)", nil, ErrDivisionByZero},
+	{"floor(1/0)", nil, ErrDivisionByZero},
+	{"1 + (1 < 2)", nil, ErrType},
+	{"!1", nil, ErrType},
+	{"1 ? 2 : 3", nil, ErrType},
+	{"1d20>=15 ? 1 : 0", nil, ErrType},
+	{"(1 < 2) == 1", nil, ErrType},
+	{"(1 < 2) != 1", nil, ErrType},
+	{"2^-1", nil, errors.ErrUnsupported},
+	{"9223372036854775807 + 1", nil, ErrOverflow},
+	{"-9223372036854775807 - 2", nil, ErrOverflow},
+	{"0 - (-9223372036854775807 - 1)", nil, ErrOverflow},
+	{"4611686018427387904 * 2", nil, ErrOverflow},
+	{"2^63", nil, ErrOverflow},
+	{"3^40", nil, ErrOverflow},
+	{"abs(-9223372036854775807 - 1)", nil, ErrOverflow},
+	{"(-9223372036854775807 - 1) / -1", nil, ErrOverflow},
+	{"floor((-9223372036854775807 - 1) / -1)", nil, ErrOverflow},
+	{"-(-9223372036854775807 - 1)", nil, ErrOverflow},
+	{"2d{9223372036854775807}", nil, ErrOverflow},
+	{"2d{9223372036854775807}kh2", nil, ErrOverflow},
+	{"d{1,4611686018427387904}!!", []Option{WithRoller(highRoller{})}, ErrOverflow},
+	{"d6!", []Option{WithRoller(highRoller{}), WithMaxExplosions(3)}, ErrLimitExceeded},
+	{"d6!", []Option{WithRoller(highRoller{}), WithMaxExplosions(3)}, ErrTooManyExplosions},
+	{"d6r<7", nil, ErrLimitExceeded},
+	{"d6r<7", nil, ErrTooManyRerolls},
+	{"999999999d999999999", nil, ErrTooManyDice},
+	{"10001d6", nil, ErrLimitExceeded},
+	{"10d6!", []Option{WithRoller(highRoller{}), WithMaxDice(20)}, ErrTooManyDice},
+	{"3d6 + 3d6", []Option{WithMaxDice(5)}, ErrTooManyDice},
+	{"d2000000", nil, ErrTooManyFaces},
+	{"d{1,2,3}", []Option{WithMaxFaces(2)}, ErrTooManyFaces},
+}
+
+var distributionSentinelTestCases = []sentinelTestCase{
+	{"1d6/(1d2-1)", nil, ErrDivisionByZero},
+	{"1d6 + (1d2 == 1)", nil, ErrType},
+	{"1d2 == (1d2 == 1)", nil, ErrType},
+	{"2d6!kh1", nil, errors.ErrUnsupported},
+	{"100d100", []Option{WithMaxOutcomes(10)}, ErrLimitExceeded},
+	{"2d6 * 2d6", []Option{WithMaxCombinations(10)}, ErrLimitExceeded},
+	{"1d3000 * 1d3000", nil, ErrLimitExceeded},
+	{"10d100!!kh5", nil, ErrLimitExceeded},
+	{"200d20kh100", nil, ErrLimitExceeded},
+	{"d6r<7", nil, ErrLimitExceeded},
+	{"d1!", nil, ErrLimitExceeded},
+	{"d1!", nil, ErrTooManyExplosions},
+	{"2d{9223372036854775807}", nil, ErrOverflow},
+	{"2d{9223372036854775807}kh2", nil, ErrOverflow},
+	{"d{1,4611686018427387904}!!", nil, ErrOverflow},
+	{"d6r<7", nil, ErrTooManyRerolls},
+	{"999999999d999999999", nil, ErrTooManyDice},
+	{"d10", []Option{WithMaxFaces(6)}, ErrTooManyFaces},
+}
+
+func TestCompileErrorsWrapSentinels(t *testing.T) {
+	for _, tc := range compileSentinelTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := Compile([]byte(tc.input), tc.opts...)
+			if !errors.Is(err, tc.sentinel) {
+				t.Fatalf("Expected error matching %v but found %v.\n", tc.sentinel, err)
+			}
+		})
+	}
+}
+
+func TestRollErrorsWrapSentinels(t *testing.T) {
+	for _, tc := range rollSentinelTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expression, err := Compile([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			_, err = expression.Roll(tc.opts...)
+			if !errors.Is(err, tc.sentinel) {
+				t.Fatalf("Expected error matching %v but found %v.\n", tc.sentinel, err)
+			}
+			if errors.Is(err, ErrSyntax) {
+				t.Fatalf("Expected evaluation error not to match ErrSyntax but found %v.\n", err)
+			}
+		})
+	}
+}
+
+func TestDistributionErrorsWrapSentinels(t *testing.T) {
+	for _, tc := range distributionSentinelTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expression, err := Compile([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			_, err = expression.Distribution(tc.opts...)
+			if !errors.Is(err, tc.sentinel) {
+				t.Fatalf("Expected error matching %v but found %v.\n", tc.sentinel, err)
+			}
+		})
+	}
+}
+
+// cancellingRoller cancels its context after rolling a number of dice
+type cancellingRoller struct {
+	rolls  *int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (roller cancellingRoller) IntN(n int) int {
+	*roller.rolls++
+	if *roller.rolls == roller.after {
+		roller.cancel()
+	}
+	return 0
+}
+
+func TestEvaluationStopsWhenContextIsDone(t *testing.T) {
+	expression, err := Compile([]byte("9000d6 + 1d6"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rolls := 0
+	_, err = expression.Roll(WithContext(ctx), WithRoller(cancellingRoller{&rolls, 100, cancel}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled but found %v.\n", err)
+	}
+	if rolls >= 9000 {
+		t.Fatalf("Expected rolling to stop soon after the context was done but found %d rolls.\n", rolls)
+	}
+
+	if _, err := expression.Distribution(WithContext(ctx)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled but found %v.\n", err)
+	}
+}
+
+// doneAfterContext is a context that is done once Err has been called a number of times
+type doneAfterContext struct {
+	context.Context
+	calls *int
+	after int
+}
+
+func (ctx doneAfterContext) Err() error {
+	*ctx.calls++
+	if *ctx.calls >= ctx.after {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestAnalysisStopsWhenContextIsDone(t *testing.T) {
+	for _, input := range []string{"1d3000 < 1d3000", "min(1d200, 1d200, 1d200)", "20d100kh10"} {
+		t.Run(input, func(t *testing.T) {
+			expression, err := Compile([]byte(input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			// the first few checks pass, so the analysis is stopped while it combines values
+			calls := 0
+			_, err = expression.Distribution(WithContext(doneAfterContext{context.Background(), &calls, 10}))
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled but found %v.\n", err)
+			}
+		})
+	}
+}
+
+func TestArgumentErrorsWrapSentinels(t *testing.T) {
+	expression, err := Compile([]byte("1d6"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	stats, err := expression.Stats()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	_, runsErr := expression.Simulate(context.Background(), 0)
+	_, workersErr := expression.Simulate(context.Background(), 10, WithWorkers(0))
+	_, emptyErr := Distribution{}.Stats()
+	_, percentileErr := stats.Percentile(101)
+	for name, err := range map[string]error{
+		"runs":       runsErr,
+		"workers":    workersErr,
+		"empty":      emptyErr,
+		"percentile": percentileErr,
+		"die name":   RegisterDie("lower", []int{1}),
+		"die faces":  RegisterDie("EMPTY", nil),
+	} {
+		if !errors.Is(err, ErrInvalidArgument) {
+			t.Fatalf("Expected %s error matching ErrInvalidArgument but found %v.\n", name, err)
+		}
+	}
+}
+
+func TestArithmeticAtTheLimitsOfInt(t *testing.T) {
+	for input, expected := range map[string]int{
+		"9223372036854775806 + 1":         math.MaxInt,
+		"-9223372036854775807 - 1":        math.MinInt,
+		"(-2)^63":                         math.MinInt,
+		"2^62":                            1 << 62,
+		"1^1000000":                       1,
+		"(-9223372036854775807 - 1) % -1": 0,
+		"round(9223372036854775807 / 2)":  1 << 62,
+	} {
+		t.Run(input, func(t *testing.T) {
+			parser := NewParser([]byte(input))
+			n, err := parser.Parse()
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+			if n != expected {
+				t.Fatalf("Expected %d but found %d.\n", expected, n)
+			}
+		})
+	}
+}
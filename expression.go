@@ -0,0 +1,64 @@
+package dice
+
+import (
+	"errors"
+	"slices"
+)
+
+// Expression is a compiled dice expression. It is scanned and parsed once by Compile and can then be rolled any
+// number of times. An Expression is never modified after Compile, so it is safe to Roll from many goroutines as
+// long as the Options passed to each Roll are, e.g. the default roller or a CryptoRoller.
+type Expression struct {
+	source string
+	root   *node
+}
+
+// Compile scans and parses the buffer into an Expression. Syntax errors and unknown named dice are returned here
+// rather than when rolling. Expressions with more tokens or deeper nesting than the limits set by WithMaxTokens and
+// WithMaxDepth return an error, the other options only apply when rolling.
+func Compile(buffer []byte, opts ...Option) (*Expression, error) {
+	p := NewParser(buffer, opts...)
+	root, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{string(buffer), root}, nil
+}
+
+// CompileWithRecovery compiles the buffer like Compile but carries on after a syntax error, so every syntax error in
+// the buffer is returned at once, e.g. for an editor to underline them all. The Expression is returned even when
+// there are errors. Rolling or analysing a part of it with a syntax error returns an error wrapping ErrSyntax.
+// The error is nil when there are no syntax errors and a SyntaxErrors otherwise, so use errors.As to get every one.
+// Errors that aren't syntax errors, like an expression past the limits set by WithMaxTokens and WithMaxDepth, are never
+// recovered from. They are returned as the last SyntaxError, which wraps them and spans the whole buffer.
+func CompileWithRecovery(buffer []byte, opts ...Option) (*Expression, error) {
+	p := NewParser(buffer, opts...)
+	p.recovering = true
+	root, err := p.compile()
+	if err != nil {
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			syntaxErr = newSyntaxError(buffer, 0, len(buffer), "", "", "%s", err.Error())
+			syntaxErr.Err = err
+		}
+		return &Expression{string(buffer), placeholder(token{invalid, string(buffer), 0, len(buffer)})}, append(SyntaxErrors(p.diagnostics), syntaxErr)
+	}
+	if len(p.diagnostics) == 0 {
+		return &Expression{string(buffer), root}, nil
+	}
+	// the scanner finds its errors before the parser starts, so put them back in the order of the input
+	slices.SortStableFunc(p.diagnostics, func(a *SyntaxError, b *SyntaxError) int {
+		return a.Start - b.Start
+	})
+	return &Expression{string(buffer), root}, SyntaxErrors(p.diagnostics)
+}
+
+// Roll rolls every dice term of the expression and returns the Result tree
+func (expression *Expression) Roll(opts ...Option) (*Result, error) {
+	return walk(expression.root, applyOptions(opts))
+}
+
+// String returns the source the expression was compiled from
+func (expression *Expression) String() string {
+	return expression.source
+}
@@ -0,0 +1,152 @@
+package dice
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+var invalidCompileTestCases = []string{
+	"(",
+	"1 1",
+	"2d6kx",
+	"dNOTADIE",
+	"max(1",
+}
+
+func TestCompileWithInvalidInput(t *testing.T) {
+	for _, input := range invalidCompileTestCases {
+		t.Run(input, func(t *testing.T) {
+			_, err := Compile([]byte(input))
+			if err == nil {
+				t.Fatalf("Expected error but found none.\n")
+			}
+		})
+	}
+}
+
+type recoveryTestCase struct {
+	input string
+	spans [][2]int
+}
+
+var recoveryTestCases = []recoveryTestCase{
+	{"1d6 + * 2 + 3 3", [][2]int{{6, 7}, {14, 15}}},
+	{"1d6 @ 2 # 3", [][2]int{{4, 5}, {8, 9}}},
+	{"max(1, ) + 1 = 2", [][2]int{{7, 8}, {13, 14}}},
+	{"foo(1) + 1d0", [][2]int{{0, 3}, {9, 12}}},
+	{"1) + 2)", [][2]int{{1, 2}, {6, 7}}},
+	{"(1d6 + 2", [][2]int{{8, 8}}},
+	{"((((", [][2]int{{4, 4}}},
+	{"1d20 >= 10 ? 1", [][2]int{{14, 14}}},
+	{"2d6kx + 1d6$ - min 3", [][2]int{{0, 5}, {11, 12}, {19, 20}}},
+}
+
+func TestCompileWithRecovery(t *testing.T) {
+	for _, tc := range recoveryTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expression, err := CompileWithRecovery([]byte(tc.input))
+			var errs SyntaxErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Expected SyntaxErrors but found %v.\n", err)
+			}
+			if len(errs) != len(tc.spans) {
+				t.Fatalf("Expected %d errors but found %d:\n%s\n", len(tc.spans), len(errs), errs.Error())
+			}
+			for i, err := range errs {
+				if err.Start != tc.spans[i][0] || err.End != tc.spans[i][1] {
+					t.Fatalf("Expected error %d to span %d to %d but found %d to %d.\n", i, tc.spans[i][0], tc.spans[i][1], err.Start, err.End)
+				}
+			}
+
+			if !errors.Is(err, ErrSyntax) {
+				t.Fatalf("Expected errors to match ErrSyntax.\n")
+			}
+			if _, err := expression.Roll(); !errors.Is(err, ErrSyntax) {
+				t.Fatalf("Expected rolling to return ErrSyntax but found %v.\n", err)
+			}
+		})
+	}
+}
+
+func TestCompileWithRecoveryFindsTheSameFirstError(t *testing.T) {
+	for _, tc := range syntaxErrorTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			_, err := CompileWithRecovery([]byte(tc.input))
+			var errs SyntaxErrors
+			if !errors.As(err, &errs) || len(errs) == 0 {
+				t.Fatalf("Expected errors but found none.\n")
+			}
+			if errs[0].Start != tc.start || errs[0].End != tc.end || errs[0].Found != tc.found {
+				t.Fatalf("Expected first error %s at %d to %d but found %s at %d to %d.\n", tc.found, tc.start, tc.end, errs[0].Found, errs[0].Start, errs[0].End)
+			}
+		})
+	}
+}
+
+func TestCompileWithRecoveryWithValidInput(t *testing.T) {
+	expression, err := CompileWithRecovery([]byte("4d6kh3 + max(1d20, 2) >= 10 ? 1 : 0"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	if _, err := expression.Roll(); err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+}
+
+func TestExpressionRollsMany(t *testing.T) {
+	expression, err := Compile([]byte("4d6kh3+2"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if expression.String() != "4d6kh3+2" {
+		t.Fatalf("Expected expression source to be 4d6kh3+2 but found %s.\n", expression.String())
+	}
+
+	for range 100 {
+		res, err := expression.Roll()
+		if err != nil {
+			t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+		}
+		if v := res.Value.Int(); v < 5 || v > 20 {
+			t.Fatalf("Expected result between 5 and 20 but found %d.\n", v)
+		}
+	}
+
+	res, err := expression.Roll(WithRoller(highRoller{}))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	if res.Value.Int() != 20 {
+		t.Fatalf("Expected result with the high roller to be 20 but found %d.\n", res.Value.Int())
+	}
+}
+
+func TestExpressionRollsConcurrently(t *testing.T) {
+	expression, err := Compile([]byte("10d10!10>=8f1 + max(1d20, 1d20)"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if _, err := expression.Roll(WithRoller(CryptoRoller{})); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+}
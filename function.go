@@ -0,0 +1,147 @@
+package dice
+
+import "math"
+
+// function is a built-in that can be called from an expression, e.g. max(1d6, 3). Functions that take more than
+// one argument must fold over them, e.g. max(a, b, c) == max(max(a, b), c), so exact analysis can combine the
+// arguments one at a time.
+//
+// Functions with divide set, floor, ceil and round, only take a single division, e.g. floor(1d20/2), and round the
+// exact quotient of its two sides instead of the truncated result of /. The quotient isn't carried through any other
+// operator, so floor(-7/2+0) would be the truncated -3. Rather than return such an argument unchanged, checkArguments
+// rejects anything but a division written directly in the call.
+type function struct {
+	minArgs int
+	maxArgs int // -1 allows any number of arguments
+	call    func(args []int) (int, error)
+	divide  func(lhs int, rhs int) (int, error)
+}
+
+var functions = map[string]function{
+	"min":   {1, -1, minOf, nil},
+	"max":   {1, -1, maxOf, nil},
+	"abs":   {1, 1, abs, nil},
+	"floor": {1, 1, nil, floorDivide},
+	"ceil":  {1, 1, nil, ceilDivide},
+	"round": {1, 1, nil, roundDivide},
+}
+
+// checkArity returns an error when a call to the named function has the wrong number of arguments
+func checkArity(name string, argCount int) error {
+	fn, ok := functions[name]
+	if !ok {
+		return errorf(ErrUnknownFunction, "Unknown function %s.", name)
+	}
+
+	if argCount < fn.minArgs {
+		return errorf(ErrSyntax, "Function %s needs at least %d argument(s) but was called with %d.", name, fn.minArgs, argCount)
+	}
+
+	if fn.maxArgs != -1 && argCount > fn.maxArgs {
+		return errorf(ErrSyntax, "Function %s takes at most %d argument(s) but was called with %d.", name, fn.maxArgs, argCount)
+	}
+	return nil
+}
+
+// checkArguments returns an error when a call to the named function has the wrong number or shape of arguments
+func checkArguments(name string, args []*node) error {
+	if err := checkArity(name, len(args)); err != nil {
+		return err
+	}
+	if functions[name].divide != nil && !isDivision(args[0]) {
+		return errorf(ErrSyntax, "Function %s only takes a division, e.g. %s(1d20/2).", name, name)
+	}
+	return nil
+}
+
+// isDivision reports whether the node is a / operator
+func isDivision(n *node) bool {
+	return n != nil && n.token.kind == operator && n.token.value == "/"
+}
+
+func minOf(args []int) (int, error) {
+	result := args[0]
+	for _, arg := range args[1:] {
+		result = min(result, arg)
+	}
+	return result, nil
+}
+
+func maxOf(args []int) (int, error) {
+	result := args[0]
+	for _, arg := range args[1:] {
+		result = max(result, arg)
+	}
+	return result, nil
+}
+
+func abs(args []int) (int, error) {
+	if args[0] == math.MinInt {
+		return 0, errorf(ErrOverflow, "Integer overflow in abs(%d).", args[0])
+	}
+	if args[0] < 0 {
+		return -args[0], nil
+	}
+	return args[0], nil
+}
+
+// absUint returns the absolute value of n, which doesn't overflow for math.MinInt
+func absUint(n int) uint {
+	if n < 0 {
+		return uint(-n)
+	}
+	return uint(n)
+}
+
+// checkDivision returns an error when lhs / rhs is a division by zero or doesn't fit in an int
+func checkDivision(lhs int, rhs int) error {
+	if rhs == 0 {
+		return errorf(ErrDivisionByZero, "Division by zero.")
+	}
+	if lhs == math.MinInt && rhs == -1 {
+		return errorf(ErrOverflow, "Integer overflow in %d / %d.", lhs, rhs)
+	}
+	return nil
+}
+
+func floorDivide(lhs int, rhs int) (int, error) {
+	if err := checkDivision(lhs, rhs); err != nil {
+		return 0, err
+	}
+
+	q, r := lhs/rhs, lhs%rhs
+	if r != 0 && (r < 0) != (rhs < 0) {
+		q--
+	}
+	return q, nil
+}
+
+func ceilDivide(lhs int, rhs int) (int, error) {
+	if err := checkDivision(lhs, rhs); err != nil {
+		return 0, err
+	}
+
+	q, r := lhs/rhs, lhs%rhs
+	if r != 0 && (r < 0) == (rhs < 0) {
+		q++
+	}
+	return q, nil
+}
+
+// roundDivide rounds the quotient to the nearest integer, with halves rounded away from zero
+func roundDivide(lhs int, rhs int) (int, error) {
+	if err := checkDivision(lhs, rhs); err != nil {
+		return 0, err
+	}
+
+	// compare the remainder with the rest of the divisor as uints, which hold the absolute value of math.MinInt
+	q, r := lhs/rhs, absUint(lhs%rhs)
+	if r >= absUint(rhs)-r {
+		if (lhs < 0) != (rhs < 0) {
+			q--
+		} else {
+			q++
+		}
+	}
+	return q, nil
+}
@@ -0,0 +1,86 @@
+package dice
+
+import (
+	"testing"
+)
+
+type divideTestCase struct {
+	name     string
+	divide   func(int, int) (int, error)
+	lhs      int
+	rhs      int
+	expected int
+}
+
+var divideTestCases = []divideTestCase{
+	{"floorDivide rounds positive quotients down", floorDivide, 7, 2, 3},
+	{"floorDivide rounds negative quotients down", floorDivide, -7, 2, -4},
+	{"floorDivide leaves exact quotients alone", floorDivide, -8, 2, -4},
+	{"ceilDivide rounds positive quotients up", ceilDivide, 7, 2, 4},
+	{"ceilDivide rounds negative quotients up", ceilDivide, 7, -2, -3},
+	{"roundDivide rounds halves away from zero", roundDivide, 7, 2, 4},
+	{"roundDivide rounds negative halves away from zero", roundDivide, -7, 2, -4},
+	{"roundDivide rounds to the nearest integer", roundDivide, 10, 3, 3},
+	{"roundDivide rounds negative quotients to the nearest integer", roundDivide, 11, -3, -4},
+}
+
+func TestDivideFunctions(t *testing.T) {
+	for _, tc := range divideTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.divide(tc.lhs, tc.rhs)
+			if err != nil {
+				t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+			}
+
+			if res != tc.expected {
+				t.Fatalf("Expected %d / %d to be %d but was %d.\n", tc.lhs, tc.rhs, tc.expected, res)
+			}
+		})
+	}
+}
+
+func TestDivideFunctionsByZero(t *testing.T) {
+	for _, divide := range []func(int, int) (int, error){floorDivide, ceilDivide, roundDivide} {
+		if _, err := divide(1, 0); err == nil {
+			t.Fatalf("Expected err to not be nil but it was.\n")
+		}
+	}
+}
+
+type arityTestCase struct {
+	name     string
+	function string
+	argCount int
+}
+
+var invalidArityTestCases = []arityTestCase{
+	{"Unknown function returns error", "sqrt", 1},
+	{"Too few arguments returns error", "max", 0},
+	{"Too many arguments returns error", "abs", 2},
+}
+
+var validArityTestCases = []arityTestCase{
+	{"Variadic function accepts a single argument", "min", 1},
+	{"Variadic function accepts many arguments", "max", 5},
+	{"Unary function accepts a single argument", "floor", 1},
+}
+
+func TestCheckArityWithInvalidCall(t *testing.T) {
+	for _, tc := range invalidArityTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkArity(tc.function, tc.argCount); err == nil {
+				t.Fatalf("Expected err to not be nil but it was.\n")
+			}
+		})
+	}
+}
+
+func TestCheckArityWithValidCall(t *testing.T) {
+	for _, tc := range validArityTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := checkArity(tc.function, tc.argCount); err != nil {
+				t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package dice
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultBarWidth = 40
+
+// unicodeEighths are the block characters for 1/8 to 7/8 of a character cell, used for the end of Unicode bars
+var unicodeEighths = []rune{'▏', '▎', '▍', '▌', '▋', '▊', '▉'}
+
+// histogramOptions holds the settings used while rendering a histogram
+type histogramOptions struct {
+	width       int
+	percentages bool
+	atLeast     bool
+	unicode     bool
+}
+
+// HistogramOption changes a setting used while rendering a histogram
+type HistogramOption func(*histogramOptions)
+
+// WithBarWidth sets how many characters the longest bar takes up. It defaults to 40.
+func WithBarWidth(width int) HistogramOption {
+	return func(o *histogramOptions) {
+		o.width = width
+	}
+}
+
+// WithPercentages shows or hides the probability of every value as a percentage after its bar. It defaults to shown.
+func WithPercentages(show bool) HistogramOption {
+	return func(o *histogramOptions) {
+		o.percentages = show
+	}
+}
+
+// WithAtLeast draws the probability of rolling each value or more instead of the probability of rolling exactly it.
+// Boolean outcomes are left out.
+func WithAtLeast(atLeast bool) HistogramOption {
+	return func(o *histogramOptions) {
+		o.atLeast = atLeast
+	}
+}
+
+// WithUnicodeBars draws bars with Unicode block characters, which can end in eighths of a character, instead of #
+func WithUnicodeBars(unicode bool) HistogramOption {
+	return func(o *histogramOptions) {
+		o.unicode = unicode
+	}
+}
+
+// Histogram renders the distribution as horizontal bars, one line per value, e.g.
+//
+//	10 | ####################                       8.33%
+//	12 | #######                                    2.78%
+//
+// Bars are scaled so the most likely value, 7 for 2d6, gets the full width. It works the same for an exact
+// Distribution and the Distribution of a Simulation.
+func (distribution Distribution) Histogram(opts ...HistogramOption) string {
+	o := histogramOptions{width: defaultBarWidth, percentages: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.atLeast {
+		// rolling a boolean or more means nothing, so only integers are drawn
+		distribution = slices.DeleteFunc(slices.Clone(distribution), func(outcome Outcome) bool {
+			return outcome.Value.IsBool()
+		})
+	}
+	if len(distribution) == 0 {
+		return ""
+	}
+
+	probabilities := make([]float64, len(distribution))
+	for i, outcome := range distribution {
+		probabilities[i] = outcome.Probability
+	}
+	if o.atLeast {
+		// outcomes are sorted, so rolling a value or more is the sum from its outcome to the end
+		for i := len(probabilities) - 2; i >= 0; i-- {
+			probabilities[i] += probabilities[i+1]
+		}
+	}
+
+	labelWidth := 0
+	highest := 0.0
+	for i, outcome := range distribution {
+		labelWidth = max(labelWidth, len(outcome.Value.String()))
+		highest = max(highest, probabilities[i])
+	}
+
+	var sb strings.Builder
+	for i, outcome := range distribution {
+		fmt.Fprintf(&sb, "%*s | ", labelWidth, outcome.Value.String())
+
+		b := bar(probabilities[i]/highest*float64(max(0, o.width)), o.unicode)
+		sb.WriteString(b)
+		if o.percentages {
+			// pad by runes since Unicode blocks take more than one byte
+			sb.WriteString(strings.Repeat(" ", max(0, o.width-utf8.RuneCountInString(b))))
+			fmt.Fprintf(&sb, " %6.2f%%", probabilities[i]*100)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// bar returns a bar of the given length in characters, rounded to whole characters or, in Unicode, eighths of one
+func bar(length float64, unicode bool) string {
+	if !unicode {
+		return strings.Repeat("#", int(math.Round(length)))
+	}
+
+	eighths := int(math.Round(length * 8))
+	s := strings.Repeat("█", eighths/8)
+	if eighths%8 > 0 {
+		s += string(unicodeEighths[eighths%8-1])
+	}
+	return s
+}
@@ -0,0 +1,104 @@
+package dice
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type histogramTestCase struct {
+	name     string
+	input    string
+	opts     []HistogramOption
+	expected string
+}
+
+var histogramTestCases = []histogramTestCase{
+	{"Default shows 40 character bars with percentages", "1d2", nil, "" +
+		"1 | ########################################  50.00%\n" +
+		"2 | ########################################  50.00%\n"},
+	{"Bars are scaled to the most likely value", "1d2 + 1d2", []HistogramOption{WithBarWidth(4)}, "" +
+		"2 | ##    25.00%\n" +
+		"3 | ####  50.00%\n" +
+		"4 | ##    25.00%\n"},
+	{"Percentages can be hidden", "1d2 + 1d2", []HistogramOption{WithBarWidth(4), WithPercentages(false)}, "" +
+		"2 | ##\n" +
+		"3 | ####\n" +
+		"4 | ##\n"},
+	{"At least mode draws the chance of each value or more", "1d4", []HistogramOption{WithBarWidth(4), WithAtLeast(true)}, "" +
+		"1 | #### 100.00%\n" +
+		"2 | ###   75.00%\n" +
+		"3 | ##    50.00%\n" +
+		"4 | #     25.00%\n"},
+	{"Unicode bars end in eighths and are padded by characters", "1d4", []HistogramOption{WithBarWidth(2), WithAtLeast(true), WithUnicodeBars(true)}, "" +
+		"1 | ██ 100.00%\n" +
+		"2 | █▌  75.00%\n" +
+		"3 | █   50.00%\n" +
+		"4 | ▌   25.00%\n"},
+	{"Labels are right aligned", "1d20 >= 11 ? 10 : 9", []HistogramOption{WithBarWidth(2), WithPercentages(false)}, "" +
+		" 9 | ##\n" +
+		"10 | ##\n"},
+	{"Booleans are drawn like any other value", "1d4 == 1", []HistogramOption{WithBarWidth(3), WithPercentages(false)}, "" +
+		"false | ###\n" +
+		" true | #\n"},
+	{"At least mode leaves out booleans", "1d2 == 1 ? 1d3 : 1 < 2", []HistogramOption{WithBarWidth(3), WithAtLeast(true)}, "" +
+		"1 | ###  50.00%\n" +
+		"2 | ##   33.33%\n" +
+		"3 | #    16.67%\n"},
+	{"At least mode draws nothing for a boolean expression", "1d20 >= 11", []HistogramOption{WithAtLeast(true)}, ""},
+}
+
+func TestHistogram(t *testing.T) {
+	for _, tc := range histogramTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expression, err := Compile([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+			distribution, err := expression.Distribution()
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			if found := distribution.Histogram(tc.opts...); found != tc.expected {
+				t.Fatalf("Expected histogram\n%s\nbut found\n%s\n", tc.expected, found)
+			}
+		})
+	}
+}
+
+func TestHistogramOfSimulation(t *testing.T) {
+	expression, err := Compile([]byte("4d6kh3"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	simulation, err := expression.Simulate(context.Background(), 1000, WithWorkerRollers(func(int) Roller {
+		return highRoller{}
+	}))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	expected := "18 | ##### 100.00%\n"
+	if found := simulation.Distribution.Histogram(WithBarWidth(5)); found != expected {
+		t.Fatalf("Expected histogram\n%s\nbut found\n%s\n", expected, found)
+	}
+}
+
+func TestHistogramOfEmptyDistribution(t *testing.T) {
+	if found := Distribution(nil).Histogram(); found != "" {
+		t.Fatalf("Expected empty histogram but found %s.\n", found)
+	}
+
+	expression, err := Compile([]byte("2d6"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	distribution, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	if lines := strings.Count(distribution.Histogram(WithBarWidth(0)), "\n"); lines != 11 {
+		t.Fatalf("Expected 11 lines with no bars but found %d.\n", lines)
+	}
+}
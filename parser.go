@@ -1,108 +1,606 @@
 package dice
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+)
 
 // Picking a defualt slice size that will fit most common dice expressions
 const defaultTokenSliceSize = 10
 
-// TODO make `.buffer` into `.Buffer` to allow switching out the byte buffer instead of needing to create new Parser structs to parse mutliple inputs.
-// would also need to provide a `.Reset` function to allow the internal state of the Parser (mainly the tokens and currentTokenPos) to be zeroed out.
-// buffer could not be reset/zeroed because the intention would be to swap that out after calling .Reset()
+// Buffer can be swapped out to parse another input with the same parser. Call Reset before parsing again, or set
+// AutoReset so every parse resets the parser first. Parsing a used parser without a reset returns an error.
+// Reset does not touch Buffer, the intention is to swap it out after calling Reset.
 type parser struct {
-	buffer          []byte
+	Buffer          []byte
+	AutoReset       bool
 	tokens          []token
 	currentTokenPos int
+	options         options
+	// recovering parsers record syntax errors in diagnostics and carry on instead of returning the first one
+	recovering  bool
+	diagnostics []*SyntaxError
+	// depth is how deeply the operand being parsed is nested
+	depth int
 }
 
-func NewParser(buffer []byte) parser {
-	return parser{buffer, make([]token, 0, defaultTokenSliceSize), 0}
+// The default limits on how many times in a row a single die can explode or be rerolled
+const (
+	defaultMaxExplosions = 100
+	defaultMaxRerolls    = 100
+)
+
+// The default limits on the size of the exact analysis of an expression
+const (
+	defaultMaxOutcomes     = 100_000
+	defaultMaxCombinations = 10_000_000
+)
+
+// The default limits on the size of an expression and the dice it rolls, so untrusted input can't take up unbounded
+// time or memory
+const (
+	defaultMaxDice   = 10_000
+	defaultMaxFaces  = 1_000_000
+	defaultMaxDepth  = 100
+	defaultMaxTokens = 1_000
+)
+
+// rolledDiceCheckInterval is how many dice are rolled between checks of the context
+const rolledDiceCheckInterval = 1024
+
+// options holds the settings used while evaluating an expression
+type options struct {
+	maxExplosions   int
+	maxRerolls      int
+	maxOutcomes     int
+	maxCombinations int
+	maxDice         int
+	maxFaces        int
+	maxDepth        int
+	maxTokens       int
+	ctx             context.Context
+	roller          Roller
+	// rolled counts the dice rolled by a single evaluation. The outermost walk sets it.
+	rolled *int
+	// workers, workerRoller and progress are only used by Simulate
+	workers      int
+	workerRoller func(worker int) Roller
+	progress     func(done int, total int)
+}
+
+func defaultOptions() options {
+	return options{
+		maxExplosions:   defaultMaxExplosions,
+		maxRerolls:      defaultMaxRerolls,
+		maxOutcomes:     defaultMaxOutcomes,
+		maxCombinations: defaultMaxCombinations,
+		maxDice:         defaultMaxDice,
+		maxFaces:        defaultMaxFaces,
+		maxDepth:        defaultMaxDepth,
+		maxTokens:       defaultMaxTokens,
+		ctx:             context.Background(),
+		roller:          globalRoller{},
+		workers:         runtime.GOMAXPROCS(0),
+	}
+}
+
+// Option changes a setting used while evaluating an expression
+type Option func(*options)
+
+// WithMaxExplosions limits how many times in a row a single die can explode before evaluation returns an error
+func WithMaxExplosions(n int) Option {
+	return func(o *options) {
+		o.maxExplosions = n
+	}
+}
+
+// WithMaxRerolls limits how many times a single die can be rerolled before evaluation returns an error
+func WithMaxRerolls(n int) Option {
+	return func(o *options) {
+		o.maxRerolls = n
+	}
+}
+
+// WithMaxOutcomes limits how many distinct values any part of an expression can have during exact analysis before
+// the analysis returns an error
+func WithMaxOutcomes(n int) Option {
+	return func(o *options) {
+		o.maxOutcomes = n
+	}
+}
+
+// WithMaxCombinations limits how many pairs of values a single step of exact analysis can combine, e.g. the outcomes
+// of both sides of an operator, before the analysis returns an error. Keeping or dropping dice shares one such
+// budget across all of its steps.
+func WithMaxCombinations(n int) Option {
+	return func(o *options) {
+		o.maxCombinations = n
+	}
+}
+
+// WithMaxDice limits how many dice an expression can roll, counting every explosion and reroll, before evaluation
+// returns an error. Exact analysis returns an error for any dice term with more dice than the limit. It defaults to
+// 10,000.
+func WithMaxDice(n int) Option {
+	return func(o *options) {
+		o.maxDice = n
+	}
+}
+
+// WithMaxFaces limits how many faces the dice of an expression can have before evaluation returns an error. It
+// defaults to 1,000,000.
+func WithMaxFaces(n int) Option {
+	return func(o *options) {
+		o.maxFaces = n
+	}
+}
+
+// WithMaxDepth limits how deeply parens, function calls, prefix operators and right associative operators can nest
+// before parsing returns an error. It defaults to 100.
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
+
+// WithMaxTokens limits how many tokens, like literals, dice terms and operators, an expression can have before
+// parsing returns an error. It defaults to 1,000.
+func WithMaxTokens(n int) Option {
+	return func(o *options) {
+		o.maxTokens = n
+	}
+}
+
+// WithContext stops evaluation and exact analysis with the error of ctx once ctx is done, e.g. to limit how long
+// they can take with context.WithTimeout. Simulate uses its own ctx argument instead.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithRoller rolls dice with the given Roller instead of the top-level functions of math/rand/v2
+func WithRoller(roller Roller) Option {
+	return func(o *options) {
+		o.roller = roller
+	}
+}
+
+// WithRandSource rolls dice with a rand.Rand built from the given source
+func WithRandSource(source rand.Source) Option {
+	return WithRoller(rand.New(source))
+}
+
+// WithSeed rolls dice with NewSeededRoller(seed) so the same expression gives the same rolls every time.
+// Simulate gives every worker its own source seeded from seed and the worker's index, so a simulation with the same
+// seed and number of workers gives the same results every time.
+func WithSeed(seed uint64) Option {
+	return func(o *options) {
+		o.roller = NewSeededRoller(seed)
+		o.workerRoller = func(worker int) Roller {
+			return rand.New(rand.NewPCG(seed, uint64(worker)))
+		}
+	}
+}
+
+// WithWorkers sets how many goroutines Simulate rolls on. It defaults to GOMAXPROCS.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
 }
 
+// WithWorkerRollers sets the Roller every worker of Simulate rolls with. newRoller is called once per worker with
+// the worker's index, and the Roller it returns is only used by that worker. By default every worker gets a
+// seeded Roller with a random seed.
+func WithWorkerRollers(newRoller func(worker int) Roller) Option {
+	return func(o *options) {
+		o.workerRoller = newRoller
+	}
+}
+
+// WithProgress makes Simulate call progress with the number of finished and total runs as the simulation goes.
+// progress is always called from the goroutine that called Simulate.
+func WithProgress(progress func(done int, total int)) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
 
+// countDie counts a rolled die against the dice limit of an evaluation and checks the context every
+// rolledDiceCheckInterval dice
+func (o options) countDie() error {
+	if *o.rolled >= o.maxDice {
+		return errorf(ErrTooManyDice, "Expression rolled more than the limit of %d dice.", o.maxDice)
+	}
+	*o.rolled++
+	if *o.rolled%rolledDiceCheckInterval == 0 {
+		return o.ctx.Err()
+	}
+	return nil
+}
+
+// applyOptions returns the default options changed by opts
+func applyOptions(opts []Option) options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func NewParser(buffer []byte, opts ...Option) parser {
+	return parser{buffer, false, make([]token, 0, defaultTokenSliceSize), 0, applyOptions(opts), false, nil, 0}
+}
+
+// Reset zeroes out the tokens and position of the last parse so the parser can parse its Buffer again
+func (parser *parser) Reset() {
+	parser.tokens = parser.tokens[:0]
+	parser.currentTokenPos = 0
+	parser.diagnostics = nil
+}
 
 type weight struct {
 	left  float64
 	right float64
 }
 
+// A right binding power lower than the left binding power makes an operator right-associative, so 2^3^2 is 2^(3^2)
 var operatorWeights = map[string]weight{
-	"+": {1.0, 1.1},
-	"-": {1.0, 1.1},
-	"*": {2.0, 2.1},
-	"/": {2.0, 2.1},
+	"?":  {0.2, 0.1},
+	"||": {0.3, 0.35},
+	"&&": {0.4, 0.45},
+	"==": {0.5, 0.55},
+	"!=": {0.5, 0.55},
+	"<":  {0.6, 0.65},
+	"<=": {0.6, 0.65},
+	">":  {0.6, 0.65},
+	">=": {0.6, 0.65},
+	"+":  {1.0, 1.1},
+	"-":  {1.0, 1.1},
+	"*":  {2.0, 2.1},
+	"/":  {2.0, 2.1},
+	"%":  {2.0, 2.1},
+	"^":  {4.0, 3.9},
+	"**": {4.0, 3.9},
+}
+
+// prefixWeights holds the right binding power of operators that can be used in prefix position.
+// They bind tighter than every infix operator except exponentiation, so -2*3 is (-2)*3 but -2^2 is -(2^2).
+var prefixWeights = map[string]float64{
+	"+": 3.0,
+	"-": 3.0,
+	"!": 3.0,
 }
 
 func (p *parser) astFromTokens(mbp float64) (*node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > p.options.maxDepth {
+		t := p.tokens[p.currentTokenPos]
+		return nil, errorf(ErrTooDeep, "Expression is nested deeper than the limit of %d at offset %d.", p.options.maxDepth, t.start)
+	}
+
 	var err error
-	root := &node{p.tokens[p.currentTokenPos], nil, nil}
+	root := &node{p.tokens[p.currentTokenPos], nil, nil, nil, nil}
 	p.currentTokenPos++
 	if root.token.kind == operator && root.token.value == "(" {
 		root, err = p.astFromTokens(0.0)
 		if err != nil {
 			return nil, err
 		}
-		temp := p.tokens[p.currentTokenPos]
-		p.currentTokenPos++
-		if temp.value != ")" {
-			return nil, fmt.Errorf("Expression should have closing paren but none were found.")
+		if temp := p.tokens[p.currentTokenPos]; temp.value == ")" {
+			p.currentTokenPos++
+		} else if err := p.report(p.syntaxError(temp, ")", "Expression should have closing paren but found %s", describe(temp))); err != nil {
+			return nil, err
+		} else {
+			root = placeholder(temp)
 		}
-
-	} else if root.token.kind != dice && root.token.kind != literal {
-		return nil, fmt.Errorf("Expression must start with a dice or literal. Found %d.", root.token.kind)
+	} else if rbp, ok := prefixWeights[root.token.value]; ok && root.token.kind == operator {
+		operand, err := p.astFromTokens(rbp)
+		if err != nil {
+			return nil, err
+		}
+		root = &node{token{unaryOperator, root.token.value, root.token.start, root.token.end}, nil, operand, nil, nil}
+	} else if root.token.kind == identifier {
+		reported := len(p.diagnostics)
+		root.args, err = p.callArguments()
+		if err != nil {
+			return nil, err
+		}
+		// the arguments of a call with a missing ( or ) are incomplete, so their count isn't worth reporting
+		if len(p.diagnostics) > reported {
+			root = placeholder(root.token)
+		} else if err = checkArguments(root.token.value, root.args); err != nil {
+			if err := p.report(wrapSyntaxError(p.Buffer, root.token.start, root.token.end, "", root.token.kind.String(), err)); err != nil {
+				return nil, err
+			}
+			root = placeholder(root.token)
+		}
+	} else if root.token.kind == dice {
+		// parse dice terms once so a compiled expression only rolls them
+		term, err := parseDiceTerm(root.token.value)
+		if err != nil {
+			if err := p.report(wrapSyntaxError(p.Buffer, root.token.start, root.token.end, "", root.token.kind.String(), err)); err != nil {
+				return nil, err
+			}
+			root = placeholder(root.token)
+		} else {
+			root.term = &term
+		}
+	} else if root.token.kind == invalid {
+		// the scanner already reported the text of an invalid token
+	} else if root.token.kind != literal {
+		if err := p.report(p.syntaxError(root.token, "dice, literal, function, ( or prefix operator", "Expression must start with a dice, literal, function, ( or prefix operator. Found %s", describe(root.token))); err != nil {
+			return nil, err
+		}
+		// a missing operand leaves the token for whatever comes after the operand
+		if _, infix := operatorWeights[root.token.value]; root.token.kind == eof || isTerminator(root.token) || (infix && root.token.kind == operator) {
+			p.currentTokenPos--
+		}
+		root = placeholder(root.token)
 	}
 
+	return p.infix(root, mbp)
+}
+
+// infix parses the operators that follow root for as long as they bind at least as tightly as mbp
+func (p *parser) infix(root *node, mbp float64) (*node, error) {
+	var err error
 	for {
 		if p.currentTokenPos == len(p.tokens) {
 			return root, nil
 		}
 
 		eofOrOp := p.tokens[p.currentTokenPos]
-		if eofOrOp.kind == eof || eofOrOp.value == ")" {
+		if eofOrOp.kind == eof || isTerminator(eofOrOp) {
 			return root, nil
-		} else if eofOrOp.kind == dice || eofOrOp.kind == literal {
-			return nil, fmt.Errorf("Expected EOF or operation token. Found %d with value %s", eofOrOp.kind, eofOrOp.value)
 		}
 
-		lbp, rbp := operatorWeights[eofOrOp.value].left, operatorWeights[eofOrOp.value].right
+		w, ok := operatorWeights[eofOrOp.value]
+		if eofOrOp.kind == operator && !ok {
+			err := errorf(ErrUnknownOperator, "Operator %s can't be used as an infix operator.", eofOrOp.value)
+			if err := p.report(wrapSyntaxError(p.Buffer, eofOrOp.start, eofOrOp.end, "EOF or operator", eofOrOp.kind.String(), err)); err != nil {
+				return nil, err
+			}
+			eofOrOp = placeholder(eofOrOp).token
+		}
+		if eofOrOp.kind == invalid {
+			// an operator that couldn't be scanned or used still gets its operands, like an addition
+			w = operatorWeights["+"]
+		} else if eofOrOp.kind != operator {
+			if err := p.report(p.syntaxError(eofOrOp, "EOF or operator", "Expected EOF or operator. Found %s", describe(eofOrOp))); err != nil {
+				return nil, err
+			}
+			root = placeholder(eofOrOp)
+			p.synchronize()
+			continue
+		}
+
+		lbp, rbp := w.left, w.right
 		if lbp < mbp {
 			break
 		}
 
 		p.currentTokenPos++
+		if eofOrOp.value == "?" {
+			root, err = p.conditionalBranches(eofOrOp, root, rbp)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
 		rhs, err := p.astFromTokens(rbp)
 		if err != nil {
 			return nil, err
 		}
 
-		root = &node{eofOrOp, root, rhs}
+		root = &node{eofOrOp, root, rhs, nil, nil}
 	}
 
 	return root, nil
 }
 
+// conditionalBranches parses the then : else part of a ternary and returns the cond ? (then : else) node
+func (p *parser) conditionalBranches(question token, cond *node, rbp float64) (*node, error) {
+	then, err := p.astFromTokens(0.0)
+	if err != nil {
+		return nil, err
+	}
+
+	colon := p.tokens[p.currentTokenPos]
+	if colon.value != ":" {
+		if err := p.report(p.syntaxError(colon, ":", "Expected : in conditional expression. Found %s", describe(colon))); err != nil {
+			return nil, err
+		}
+		// the placeholder keeps both parts so the rest of the expression is still checked, but can't be rolled
+		return &node{placeholder(question).token, cond, then, nil, nil}, nil
+	}
+	p.currentTokenPos++
+
+	otherwise, err := p.astFromTokens(rbp)
+	if err != nil {
+		return nil, err
+	}
+	return &node{question, cond, &node{colon, then, otherwise, nil, nil}, nil, nil}, nil
+}
+
+// callArguments parses the parenthesised, comma separated arguments that follow a function name
+func (p *parser) callArguments() ([]*node, error) {
+	if open := p.tokens[p.currentTokenPos]; open.value != "(" {
+		return nil, p.report(p.syntaxError(open, "(", "Expected ( after function name %s. Found %s", p.tokens[p.currentTokenPos-1].value, describe(open)))
+	}
+	p.currentTokenPos++
+
+	args := []*node{}
+	if p.tokens[p.currentTokenPos].value == ")" {
+		p.currentTokenPos++
+		return args, nil
+	}
+
+	for {
+		arg, err := p.astFromTokens(0.0)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+
+		separator := p.tokens[p.currentTokenPos]
+		switch separator.value {
+		case ",":
+			p.currentTokenPos++
+		case ")":
+			p.currentTokenPos++
+			return args, nil
+		default:
+			return args, p.report(p.syntaxError(separator, ", or )", "Expected , or ) in function arguments. Found %s", describe(separator)))
+		}
+	}
+}
+
+// report returns err, or records it and returns nil when the parser recovers from syntax errors. Only the first
+// error at an offset is recorded, since the ones after it, like the closing parens missing at the end of ((1, are
+// usually caused by it.
+func (p *parser) report(err *SyntaxError) error {
+	if !p.recovering {
+		return err
+	}
+	if !slices.ContainsFunc(p.diagnostics, func(reported *SyntaxError) bool { return reported.Start == err.Start }) {
+		p.diagnostics = append(p.diagnostics, err)
+	}
+	return nil
+}
+
+// synchronize skips tokens up to the next infix operator, closing paren, comma, colon or EOF so a recovering parser
+// can carry on after a syntax error. Parenthesised groups are skipped whole.
+func (p *parser) synchronize() {
+	depth := 0
+	for ; p.currentTokenPos < len(p.tokens); p.currentTokenPos++ {
+		t := p.tokens[p.currentTokenPos]
+		if t.kind == eof {
+			return
+		}
+		if depth == 0 {
+			if _, infix := operatorWeights[t.value]; isTerminator(t) || (infix && t.kind == operator) || t.kind == invalid {
+				return
+			}
+		}
+
+		switch {
+		case t.kind == operator && t.value == "(":
+			depth++
+		case t.kind == operator && t.value == ")":
+			depth--
+		}
+	}
+}
+
+// isTerminator reports whether the token ends an operand, like the ) of a group or the : of a conditional
+func isTerminator(t token) bool {
+	return t.kind == operator && (t.value == ")" || t.value == "," || t.value == ":")
+}
+
+// placeholder returns a node that stands in for a part of the expression with a syntax error. Rolling it returns an
+// error wrapping ErrSyntax.
+func placeholder(t token) *node {
+	return &node{token{invalid, t.value, t.start, t.end}, nil, nil, nil, nil}
+}
+
+// Parse evaluates the expression and returns its integer result.
+// Expressions that evaluate to a boolean, such as 1d20 >= 15, return an error. Use ParseValue for those.
 func (parser *parser) Parse() (int, error) {
-	// TODO when making the `.Reset` func for zeroing out, check that we have parser.currentTokenPos == 0 here or return an error
-	// Maybe have a bool autoCleanUp param and if set to true, defer a call to a .reset func to the end of this func automatically
-	// if parser.currentTokenPos != 0 {
-	// 	return 0, fmt.Errorf("Detected reuse of parser without calling .Reset().")
-	// }
-	s := scanner{parser.buffer, 0, 0}
+	v, err := parser.ParseValue()
+	if err != nil {
+		return 0, err
+	}
+
+	if v.IsBool() {
+		return 0, errorf(ErrType, "Expression evaluated to the boolean %s. Use ParseValue to get boolean results.", v)
+	}
+	return v.Int(), nil
+}
+
+// ParseValue evaluates the expression and returns its result as either an integer or a boolean Value
+func (parser *parser) ParseValue() (Value, error) {
+	result, err := parser.ParseResult()
+	if err != nil {
+		return Value{}, err
+	}
+	return result.Value, nil
+}
+
+// ParseResult evaluates the expression and returns a Result tree with the rolled dice of every dice term
+func (parser *parser) ParseResult() (*Result, error) {
+	ast, err := parser.compile()
+	if err != nil {
+		return nil, err
+	}
+	return walk(ast, parser.options)
+}
+
+// compile scans the buffer into tokens and parses them into an AST
+func (parser *parser) compile() (*node, error) {
+	// tokens of the last parse are kept until the next one so they can be inspected
+	if parser.AutoReset {
+		parser.Reset()
+	}
+	if len(parser.tokens) != 0 || parser.currentTokenPos != 0 {
+		return nil, errorf(ErrParserReused, "Detected reuse of parser without calling .Reset().")
+	}
+
+	s := scanner{parser.Buffer, 0, 0}
 	for {
 		t, err := s.readToken()
-		if err != nil {
-			return 0, err
+		var syntaxErr *SyntaxError
+		if err != nil && parser.recovering && errors.As(err, &syntaxErr) {
+			parser.diagnostics = append(parser.diagnostics, syntaxErr)
+			t = s.skipInvalid(syntaxErr)
+		} else if err != nil {
+			return nil, err
 		}
 		parser.tokens = append(parser.tokens, t)
 
 		if t.kind == eof {
 			break
 		}
+		if len(parser.tokens) > parser.options.maxTokens {
+			return nil, errorf(ErrTooManyTokens, "Expression has more than the limit of %d tokens.", parser.options.maxTokens)
+		}
 	}
 
 	ast, err := parser.astFromTokens(0.0)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return walk(ast)
+	for t := parser.tokens[parser.currentTokenPos]; t.kind != eof; t = parser.tokens[parser.currentTokenPos] {
+		if err := parser.report(parser.syntaxError(t, eof.String(), "Unexpected %s found after the end of the expression", describe(t))); err != nil {
+			return nil, err
+		}
+		// carry on with the operators after a stray token or closing paren, e.g. the + 2 of 1) + 2
+		ast = placeholder(t)
+		parser.currentTokenPos++
+		parser.synchronize()
+		if ast, err = parser.infix(ast, 0.0); err != nil {
+			return nil, err
+		}
+	}
+	return ast, nil
+}
+
+// syntaxError returns a SyntaxError pointing at the token in the parser's Buffer
+func (parser *parser) syntaxError(t token, expected string, format string, args ...any) *SyntaxError {
+	return newSyntaxError(parser.Buffer, t.start, t.end, expected, t.kind.String(), format, args...)
+}
+
+// describe names a token in error messages, e.g. literal 2 or EOF
+func describe(t token) string {
+	if t.kind == eof {
+		return t.kind.String()
+	}
+	return t.kind.String() + " " + t.value
 }
@@ -1,6 +1,7 @@
 package dice
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -15,32 +16,73 @@ type parseTestCase struct {
 
 var invalidParseTestCases = []parseTestCase{
 	{"Malformed single term input returns error", []byte("("), []token{}, 0},
-	{"Double operators in a row returns error", []byte("2**3"), []token{}, 0},
+	{"Double operators in a row returns error", []byte("2*/3"), []token{}, 0},
+	{"Division by zero returns error", []byte("1/0"), []token{}, 0},
+	{"Modulo by zero returns error", []byte("1%(2-2)"), []token{}, 0},
 	{"Unmatched parens returns error", []byte("(1+1"), []token{}, 0},
 	{"Invalid characters in input returns error", []byte("c+1"), []token{}, 0},
 	{"Missing operator between terms in input returns error", []byte("1 1"), []token{}, 0},
+	{"Prefix operator without operand returns error", []byte("-"), []token{}, 0},
+	{"Unknown function returns error", []byte("sqrt(4)"), []token{}, 0},
+	{"Function with wrong number of arguments returns error", []byte("abs(1, 2)"), []token{}, 0},
+	{"Floor of something other than a division returns error", []byte("floor(-7/2+0)"), []token{}, 0},
+	{"Round of a dice term returns error", []byte("round(1d6)"), []token{}, 0},
+	{"Function without parens returns error", []byte("max 1"), []token{}, 0},
+	{"Function with unclosed arguments returns error", []byte("max(1, 2"), []token{}, 0},
+	{"Comma outside of function arguments returns error", []byte("1, 2"), []token{}, 0},
+	{"Unmatched closing paren returns error", []byte("1+1)"), []token{}, 0},
+	{"Boolean result returns error from Parse", []byte("1 < 2"), []token{}, 0},
+	{"Arithmetic on a boolean returns error", []byte("(1 < 2) + 1"), []token{}, 0},
+	{"Logical operator on integers returns error", []byte("1 && 2"), []token{}, 0},
+	{"Conditional with integer condition returns error", []byte("1 ? 2 : 3"), []token{}, 0},
+	{"Conditional without : returns error", []byte("1 < 2 ? 3"), []token{}, 0},
+	{"Single = returns error", []byte("1 = 1"), []token{}, 0},
+	{"Prefix operator that can't be used as prefix returns error", []byte("*2"), []token{}, 0},
 }
 
 var validParseTestCases = []parseTestCase{
-	{"Single literal input returns value as int", []byte("1"), []token{{literal, "1"}, {eof, ""}}, 1},
-	{"Single operator input returns value as int", []byte("1+3"), []token{{literal, "1"}, {operator, "+"}, {literal, "3"}, {eof, ""}}, 4},
-	{"Multiple operator with same precedence input returns value as int", []byte("1+3-2"), []token{{literal, "1"}, {operator, "+"}, {literal, "3"}, {operator, "-"}, {literal, "2"}, {eof, ""}}, 2},
-	{"Multiple operator with different precedence input returns value as int", []byte("12-3*2"), []token{{literal, "12"}, {operator, "-"}, {literal, "3"}, {operator, "*"}, {literal, "2"}, {eof, ""}}, 6},
-	{"Multiple operator with different precedence and paren input returns value as int", []byte("(12-3)*2"), []token{{operator, "("}, {literal, "12"}, {operator, "-"}, {literal, "3"}, {operator, ")"}, {operator, "*"}, {literal, "2"}, {eof, ""}}, 18},
+	{"Single literal input returns value as int", []byte("1"), []token{{literal, "1", 0, 0}, {eof, "", 0, 0}}, 1},
+	{"Single operator input returns value as int", []byte("1+3"), []token{{literal, "1", 0, 0}, {operator, "+", 0, 0}, {literal, "3", 0, 0}, {eof, "", 0, 0}}, 4},
+	{"Multiple operator with same precedence input returns value as int", []byte("1+3-2"), []token{{literal, "1", 0, 0}, {operator, "+", 0, 0}, {literal, "3", 0, 0}, {operator, "-", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 2},
+	{"Multiple operator with different precedence input returns value as int", []byte("12-3*2"), []token{{literal, "12", 0, 0}, {operator, "-", 0, 0}, {literal, "3", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 6},
+	{"Multiple operator with different precedence and paren input returns value as int", []byte("(12-3)*2"), []token{{operator, "(", 0, 0}, {literal, "12", 0, 0}, {operator, "-", 0, 0}, {literal, "3", 0, 0}, {operator, ")", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 18},
+	{"Dice pool input counts successes and can be used in arithmetic", []byte("4d1>=1*2"), []token{{dice, "4d1>=1", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 8},
+	{"Unary minus at the start of input negates the first term", []byte("-2+1"), []token{{operator, "-", 0, 0}, {literal, "2", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {eof, "", 0, 0}}, -1},
+	{"Unary minus after an operator negates the next term", []byte("3d1*-1"), []token{{dice, "3d1", 0, 0}, {operator, "*", 0, 0}, {operator, "-", 0, 0}, {literal, "1", 0, 0}, {eof, "", 0, 0}}, -3},
+	{"Unary plus leaves the next term unchanged", []byte("+2"), []token{{operator, "+", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 2},
+	{"Unary minus binds tighter than multiplication", []byte("-(1+2)*2"), []token{{operator, "-", 0, 0}, {operator, "(", 0, 0}, {literal, "1", 0, 0}, {operator, "+", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, -6},
+	{"Repeated unary minus cancels out", []byte("--1"), []token{{operator, "-", 0, 0}, {operator, "-", 0, 0}, {literal, "1", 0, 0}, {eof, "", 0, 0}}, 1},
+	{"Modulo has the same precedence as multiplication", []byte("1+7%4"), []token{{literal, "1", 0, 0}, {operator, "+", 0, 0}, {literal, "7", 0, 0}, {operator, "%", 0, 0}, {literal, "4", 0, 0}, {eof, "", 0, 0}}, 4},
+	{"Exponentiation is right-associative", []byte("2^3^2"), []token{{literal, "2", 0, 0}, {operator, "^", 0, 0}, {literal, "3", 0, 0}, {operator, "^", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 512},
+	{"Exponentiation with ** binds tighter than multiplication", []byte("2**3*2"), []token{{literal, "2", 0, 0}, {operator, "**", 0, 0}, {literal, "3", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 16},
+	{"Exponentiation binds tighter than unary minus", []byte("-2^2"), []token{{operator, "-", 0, 0}, {literal, "2", 0, 0}, {operator, "^", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, -4},
+	{"Function call with many arguments returns value as int", []byte("max(1, 3d1, 2)"), []token{{identifier, "max", 0, 0}, {operator, "(", 0, 0}, {literal, "1", 0, 0}, {operator, ",", 0, 0}, {dice, "3d1", 0, 0}, {operator, ",", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, 3},
+	{"Function call can be used in arithmetic", []byte("2*min(4, 5)+1"), []token{{literal, "2", 0, 0}, {operator, "*", 0, 0}, {identifier, "min", 0, 0}, {operator, "(", 0, 0}, {literal, "4", 0, 0}, {operator, ",", 0, 0}, {literal, "5", 0, 0}, {operator, ")", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {eof, "", 0, 0}}, 9},
+	{"Function call arguments can be expressions and function calls", []byte("abs(-max(1, 2))"), []token{{identifier, "abs", 0, 0}, {operator, "(", 0, 0}, {operator, "-", 0, 0}, {identifier, "max", 0, 0}, {operator, "(", 0, 0}, {literal, "1", 0, 0}, {operator, ",", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, 2},
+	{"Floor of a division rounds the exact quotient down", []byte("floor(-7/2)"), []token{{identifier, "floor", 0, 0}, {operator, "(", 0, 0}, {operator, "-", 0, 0}, {literal, "7", 0, 0}, {operator, "/", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, -4},
+	{"Ceil of a division rounds the exact quotient up", []byte("ceil(7/2)"), []token{{identifier, "ceil", 0, 0}, {operator, "(", 0, 0}, {literal, "7", 0, 0}, {operator, "/", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, 4},
+	{"Round of a division rounds the exact quotient to the nearest integer", []byte("round(5/2)"), []token{{identifier, "round", 0, 0}, {operator, "(", 0, 0}, {literal, "5", 0, 0}, {operator, "/", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, 3},
+	{"Ceil of a division in parentheses rounds the exact quotient up", []byte("ceil((7/2))"), []token{{identifier, "ceil", 0, 0}, {operator, "(", 0, 0}, {operator, "(", 0, 0}, {literal, "7", 0, 0}, {operator, "/", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, 4},
+	{"Conditional returns the then branch when the condition is true", []byte("3d1+5 >= 8 ? 2*3 : 0"), []token{{dice, "3d1", 0, 0}, {operator, "+", 0, 0}, {literal, "5", 0, 0}, {operator, ">=", 0, 0}, {literal, "8", 0, 0}, {operator, "?", 0, 0}, {literal, "2", 0, 0}, {operator, "*", 0, 0}, {literal, "3", 0, 0}, {operator, ":", 0, 0}, {literal, "0", 0, 0}, {eof, "", 0, 0}}, 6},
+	{"Conditional returns the else branch when the condition is false", []byte("1 > 2 ? 1 : 2"), []token{{literal, "1", 0, 0}, {operator, ">", 0, 0}, {literal, "2", 0, 0}, {operator, "?", 0, 0}, {literal, "1", 0, 0}, {operator, ":", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, 2},
+	{"Nested conditionals group from the right", []byte("1 > 2 ? 1 : 2 > 3 ? 2 : 3"), []token{{literal, "1", 0, 0}, {operator, ">", 0, 0}, {literal, "2", 0, 0}, {operator, "?", 0, 0}, {literal, "1", 0, 0}, {operator, ":", 0, 0}, {literal, "2", 0, 0}, {operator, ">", 0, 0}, {literal, "3", 0, 0}, {operator, "?", 0, 0}, {literal, "2", 0, 0}, {operator, ":", 0, 0}, {literal, "3", 0, 0}, {eof, "", 0, 0}}, 3},
+	{"Dice terms roll with the parser's roller", []byte("2d6+1"), []token{{dice, "2d6", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {eof, "", 0, 0}}, 13},
+	{"Dice terms with modifiers roll with the parser's roller", []byte("4d6kh3*2dF"), []token{{dice, "4d6kh3", 0, 0}, {operator, "*", 0, 0}, {dice, "2dF", 0, 0}, {eof, "", 0, 0}}, 36},
+	{"Custom and percentile dice roll with the parser's roller", []byte("d% - d{3,9,1}"), []token{{dice, "d%", 0, 0}, {operator, "-", 0, 0}, {dice, "d{3,9,1}", 0, 0}, {eof, "", 0, 0}}, 99},
 	// TODO test (2*6)-2*(2/3) and 12/(3+3)
 }
 
 // TODO implement these
 var whiteSpaceParseTestCases = []parseTestCase{
-	{"Empty input returns 0", []byte(""), []token{{eof, ""}}, 0},
-	{"Blank input returns 0", []byte("       "), []token{{eof, ""}}, 0},
-	{"Whitespace is stripped from input and returns value as int", []byte("\n1\v\r+   1 \t  "), []token{{eof, ""}}, 2},
+	{"Empty input returns 0", []byte(""), []token{{eof, "", 0, 0}}, 0},
+	{"Blank input returns 0", []byte("       "), []token{{eof, "", 0, 0}}, 0},
+	{"Whitespace is stripped from input and returns value as int", []byte("\n1\v\r+   1 \t  "), []token{{eof, "", 0, 0}}, 2},
 }
 
 func TestParseWithValidInputString(t *testing.T) {
 	for _, tc := range validParseTestCases {
 		t.Run(tc.name, func(t *testing.T) {
-			p := NewParser(tc.input)
+			p := NewParser(tc.input, WithRoller(highRoller{}))
 			res, err := p.Parse()
 			if err != nil {
 				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
@@ -67,6 +109,44 @@ func TestParseWithValidInputString(t *testing.T) {
 	}
 }
 
+type parseValueTestCase struct {
+	name          string
+	input         []byte
+	expectedValue Value
+}
+
+var validParseValueTestCases = []parseValueTestCase{
+	{"Comparison returns a boolean", []byte("1 < 2"), boolValue(true)},
+	{"Comparison of equal values returns a boolean", []byte("3d1 == 3"), boolValue(true)},
+	{"Comparison of different values returns a boolean", []byte("1 != 1"), boolValue(false)},
+	{"Comparisons bind looser than arithmetic", []byte("1+2 <= 2*1"), boolValue(false)},
+	{"Logical and binds tighter than logical or", []byte("1 > 2 && 1 > 2 || 2 > 1"), boolValue(true)},
+	{"Logical not negates a boolean", []byte("!(1 > 2)"), boolValue(true)},
+	{"Booleans can be compared for equality", []byte("1 < 2 == 2 < 3"), boolValue(true)},
+	{"Logical and does not evaluate its right side when the left side is false", []byte("1 > 2 && 1/0 > 1"), boolValue(false)},
+	{"Integer results are returned as integers", []byte("1+1"), intValue(2)},
+}
+
+func TestParseValueWithValidInputString(t *testing.T) {
+	for _, tc := range validParseValueTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewParser(tc.input)
+			res, err := p.ParseValue()
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			if res != tc.expectedValue {
+				t.Fatalf("Result of %s does not match test case's expected result %s\n", res, tc.expectedValue)
+			}
+
+			if res.IsBool() != tc.expectedValue.IsBool() {
+				t.Fatalf("Result IsBool() = %t does not match test case's expected value\n", res.IsBool())
+			}
+		})
+	}
+}
+
 func TestParseWithInvalidInputString(t *testing.T) {
 	for _, tc := range invalidParseTestCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -78,3 +158,75 @@ func TestParseWithInvalidInputString(t *testing.T) {
 		})
 	}
 }
+
+func TestParseWithMaxExplosions(t *testing.T) {
+	p := NewParser([]byte("d1!"), WithMaxExplosions(10))
+	_, err := p.Parse()
+	if err == nil {
+		t.Fatalf("Expected error when explosions pass the limit but found none.\n")
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	p := NewParser([]byte("4d1kh3+max(2, 1d1)"))
+	res, err := p.ParseResult()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	expected := "4d1kh3 [1, 1, 1, ~~1~~] + max(2, 1d1 [1]) = 5"
+	if res.String() != expected {
+		t.Fatalf("Expected result %s but found %s\n", expected, res.String())
+	}
+
+	dice := res.Children[0]
+	if dice.Kind != DiceResult || len(dice.Dice) != 4 || dice.Value.Int() != 3 {
+		t.Fatalf("Expected first child to be a dice result with 4 dice and a subtotal of 3 but found %+v\n", dice)
+	}
+}
+
+func TestParseReuseWithoutResetReturnsError(t *testing.T) {
+	p := NewParser([]byte("1+1"))
+	if _, err := p.Parse(); err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if _, err := p.Parse(); !errors.Is(err, ErrParserReused) {
+		t.Fatalf("Expected ErrParserReused when parsing again without a reset but found %v.\n", err)
+	}
+}
+
+func TestParseReuseWithReset(t *testing.T) {
+	p := NewParser([]byte("1+1"))
+	if _, err := p.Parse(); err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	p.Reset()
+	p.Buffer = []byte("2*3")
+	res, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	if res != 6 {
+		t.Fatalf("Expected result of new buffer to be 6 but found %d\n", res)
+	}
+	if len(p.tokens) != 4 {
+		t.Fatalf("Expected only the tokens of the new buffer but found %d tokens\n", len(p.tokens))
+	}
+}
+
+func TestParseReuseWithAutoReset(t *testing.T) {
+	p := NewParser([]byte("1+1"))
+	p.AutoReset = true
+	for _, tc := range validParseTestCases[:5] {
+		p.Buffer = tc.input
+		res, err := p.Parse()
+		if err != nil {
+			t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+		}
+		if res != tc.expectedResult {
+			t.Fatalf("Result of %d does not match test case's expected result %d\n", res, tc.expectedResult)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package dice
+
+// Chance compiles a comparison written the way it is rolled, e.g. 1d20+5 >= 15 or 2d6 > 1d10, and returns the exact
+// probability that it is true. A single die with a success compare point, e.g. 1d20>=15, is read as the comparison.
+func Chance(buffer []byte, opts ...Option) (float64, error) {
+	expression, err := Compile(buffer, opts...)
+	if err != nil {
+		return 0, err
+	}
+	return expression.Chance(opts...)
+}
+
+// Chance returns the exact probability that a boolean expression is true. Integer expressions return an error.
+func (expression *Expression) Chance(opts ...Option) (float64, error) {
+	distribution, err := expression.Distribution(opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	// a single die with a success compare point has 1 success exactly when the comparison is true
+	if isSingleDieComparison(expression.root) {
+		return distribution.Probability(intValue(1)), nil
+	}
+
+	for _, outcome := range distribution {
+		if !outcome.Value.IsBool() {
+			return 0, errorf(ErrType, "Chance needs a boolean expression like 1d20+5 >= 15 but found outcome %s. %s", outcome.Value, successPointHint)
+		}
+	}
+	return distribution.Probability(boolValue(true)), nil
+}
+
+// successPointHint explains why a comparison written like 1d20>=15 evaluates to an integer
+const successPointHint = "A comparison written straight after dice is a success compare point, put a space before the operator to compare the roll."
+
+// isSuccessPool reports whether the node is a dice term that counts successes
+func isSuccessPool(root *node) bool {
+	return root.token.kind == dice && root.term != nil && root.term.countSuccesses
+}
+
+// isSingleDieComparison reports whether the expression is a single die with a success compare point, like 1d20>=15,
+// which players write to compare the roll rather than to count successes
+func isSingleDieComparison(root *node) bool {
+	if !isSuccessPool(root) {
+		return false
+	}
+	term := root.term
+	return term.count == 1 && !term.countFailures &&
+		(term.explode == explodeNone || term.explode == explodeCompound)
+}
+
+// AtLeast returns the probability of the expression evaluating to n or more. Boolean outcomes are never counted.
+func (distribution Distribution) AtLeast(n int) float64 {
+	p := 0.0
+	for _, outcome := range distribution {
+		if !outcome.Value.IsBool() && outcome.Value.Int() >= n {
+			p += outcome.Probability
+		}
+	}
+	return p
+}
+
+// AtMost returns the probability of the expression evaluating to n or less. Boolean outcomes are never counted.
+func (distribution Distribution) AtMost(n int) float64 {
+	p := 0.0
+	for _, outcome := range distribution {
+		if !outcome.Value.IsBool() && outcome.Value.Int() <= n {
+			p += outcome.Probability
+		}
+	}
+	return p
+}
+
+// Greater returns the probability of an integer rolled from distribution being greater than an independent integer
+// rolled from other. Boolean outcomes are never counted.
+func (distribution Distribution) Greater(other Distribution) float64 {
+	p := 0.0
+	// both distributions are sorted, so walk other once while adding up the probability of rolling less than each value
+	below := 0.0
+	j := 0
+	for _, outcome := range distribution {
+		if outcome.Value.IsBool() {
+			continue
+		}
+		for ; j < len(other) && (other[j].Value.IsBool() || other[j].Value.Int() < outcome.Value.Int()); j++ {
+			if !other[j].Value.IsBool() {
+				below += other[j].Probability
+			}
+		}
+		p += outcome.Probability * below
+	}
+	return p
+}
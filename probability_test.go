@@ -0,0 +1,112 @@
+package dice
+
+import (
+	"math"
+	"testing"
+)
+
+type chanceTestCase struct {
+	input    string
+	expected float64
+}
+
+var validChanceTestCases = []chanceTestCase{
+	{"1d20+5 >= 15", 11.0 / 20},
+	{"1d20 <= 5", 5.0 / 20},
+	{"2d6 > 1d6", 181.0 / 216},
+	{"1d6 > 1d6", 15.0 / 36},
+	{"1d20 == 20 || 1d20 == 20", 39.0 / 400},
+	{"!(1d20 >= 11)", 0.5},
+	{"1d20 + 5 >= 26", 0},
+	{"1d20>=15", 6.0 / 20},
+	{"d6<3", 2.0 / 6},
+	{"1d20!=20", 19.0 / 20},
+	{"1d4kh1>=3", 2.0 / 4},
+}
+
+var invalidChanceTestCases = []string{
+	"1d20+5",
+	"3d6>=5",
+	"1d20>=15f1",
+	"2d6>1d10",
+	"1d6/0 > 1",
+	"(",
+}
+
+func TestChanceWithValidInput(t *testing.T) {
+	for _, tc := range validChanceTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			p, err := Chance([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+			if math.Abs(p-tc.expected) > 1e-9 {
+				t.Fatalf("Expected chance %f but found %f.\n", tc.expected, p)
+			}
+		})
+	}
+}
+
+func TestChanceWithInvalidInput(t *testing.T) {
+	for _, input := range invalidChanceTestCases {
+		t.Run(input, func(t *testing.T) {
+			if _, err := Chance([]byte(input)); err == nil {
+				t.Fatalf("Expected error but found none.\n")
+			}
+		})
+	}
+}
+
+func TestDistributionAtLeastAndAtMost(t *testing.T) {
+	expression, err := Compile([]byte("1d20+5"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	distribution, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if p := distribution.AtLeast(15); math.Abs(p-11.0/20) > 1e-9 {
+		t.Fatalf("Expected chance of at least 15 to be %f but found %f.\n", 11.0/20, p)
+	}
+	if p := distribution.AtMost(15); math.Abs(p-10.0/20) > 1e-9 {
+		t.Fatalf("Expected chance of at most 15 to be %f but found %f.\n", 10.0/20, p)
+	}
+	if p := distribution.AtLeast(26); p != 0 {
+		t.Fatalf("Expected chance of at least 26 to be 0 but found %f.\n", p)
+	}
+	if p := distribution.AtMost(25); math.Abs(p-1) > 1e-9 {
+		t.Fatalf("Expected chance of at most 25 to be 1 but found %f.\n", p)
+	}
+}
+
+func TestDistributionGreaterMatchesChance(t *testing.T) {
+	for _, pair := range [][2]string{{"1d6", "1d6"}, {"2d6", "1d10"}, {"4d6kh3", "3d6"}, {"1d4-2", "1d2"}} {
+		t.Run(pair[0]+" > "+pair[1], func(t *testing.T) {
+			expected, err := Chance([]byte(pair[0] + " > " + pair[1]))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+			if p := chanceOfGreater(t, pair[0], pair[1]); math.Abs(p-expected) > 1e-9 {
+				t.Fatalf("Expected chance %f but found %f.\n", expected, p)
+			}
+		})
+	}
+}
+
+func chanceOfGreater(t *testing.T, a string, b string) float64 {
+	t.Helper()
+	distributions := make([]Distribution, 2)
+	for i, input := range []string{a, b} {
+		expression, err := Compile([]byte(input))
+		if err != nil {
+			t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+		}
+		distributions[i], err = expression.Distribution()
+		if err != nil {
+			t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+		}
+	}
+	return distributions[0].Greater(distributions[1])
+}
@@ -0,0 +1,130 @@
+package dice
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ResultKind is the kind of expression node a Result was evaluated from
+type ResultKind int
+
+const (
+	LiteralResult     ResultKind = iota // an integer literal
+	DiceResult                          // a dice term, with its rolled Dice
+	UnaryResult                         // a prefix operator with a single child
+	OperatorResult                      // an infix operator with a left and a right child
+	ConditionalResult                   // cond ? a : b with the condition and the picked branch as children
+	CallResult                          // a function call with its arguments as children
+)
+
+// Result is the outcome of evaluating one node of an expression. Results form a tree that mirrors the parsed expression.
+type Result struct {
+	Kind ResultKind
+	// Expression is the text of the node's token, e.g. 4d6kh3, + or max
+	Expression string
+	Value      Value
+	// Dice holds every die rolled by a DiceResult, including dropped ones, in the order they were rolled
+	Dice []Die
+	// Pool is set on a DiceResult with a success compare point
+	Pool *PoolResult
+	// Children are the evaluated operands or arguments in the order they appear in the expression.
+	// Operands that were skipped by && , || or ?: are left out.
+	Children []*Result
+}
+
+// String renders the result as its breakdown followed by its value, e.g. 4d6kh3 [6, 5, 3, ~~1~~] + 2 = 16
+func (result *Result) String() string {
+	return result.Breakdown() + " = " + result.Value.String()
+}
+
+// Breakdown renders the expression with every dice term followed by its dice.
+// Dropped and rerolled faces are struck through with ~~, exploded dice end in !, successes are **bold** and failures _italic_.
+func (result *Result) Breakdown() string {
+	var sb strings.Builder
+	result.writeBreakdown(&sb)
+	return sb.String()
+}
+
+func (result *Result) writeBreakdown(sb *strings.Builder) {
+	switch result.Kind {
+	case DiceResult:
+		sb.WriteString(result.Expression)
+		sb.WriteString(" [")
+		for i, d := range result.Dice {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeDie(sb, d)
+		}
+		sb.WriteString("]")
+	case UnaryResult:
+		sb.WriteString(result.Expression)
+		result.writeChild(sb, 0)
+	case OperatorResult:
+		result.writeChild(sb, 0)
+		if len(result.Children) == 2 {
+			sb.WriteString(" " + result.Expression + " ")
+			result.writeChild(sb, 1)
+		}
+	case ConditionalResult:
+		result.writeChild(sb, 0)
+		sb.WriteString(" ? ")
+		result.writeChild(sb, 1)
+	case CallResult:
+		sb.WriteString(result.Expression + "(")
+		for i, child := range result.Children {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			child.writeBreakdown(sb)
+		}
+		sb.WriteString(")")
+	default:
+		sb.WriteString(result.Value.String())
+	}
+}
+
+// writeChild writes the i-th child, wrapped in parens when it is an operator that would otherwise read differently
+func (result *Result) writeChild(sb *strings.Builder, i int) {
+	if i >= len(result.Children) {
+		return
+	}
+	child := result.Children[i]
+
+	parens := child.Kind == ConditionalResult && result.Kind != ConditionalResult
+	if child.Kind == OperatorResult && (result.Kind == OperatorResult || result.Kind == UnaryResult) {
+		parent, inner := operatorWeights[result.Expression], operatorWeights[child.Expression]
+		if result.Kind == UnaryResult {
+			parent = weight{prefixWeights[result.Expression], prefixWeights[result.Expression]}
+		}
+		parens = inner.left < parent.left || (inner.left == parent.left && (i == 1) == (parent.right > parent.left))
+	}
+
+	if parens {
+		sb.WriteString("(")
+	}
+	child.writeBreakdown(sb)
+	if parens {
+		sb.WriteString(")")
+	}
+}
+
+func writeDie(sb *strings.Builder, d Die) {
+	for _, r := range d.Rerolled {
+		sb.WriteString("~~" + strconv.Itoa(r) + "~~ ")
+	}
+
+	v := strconv.Itoa(d.Value)
+	if d.Exploded {
+		v += "!"
+	}
+	switch {
+	case d.Dropped:
+		v = "~~" + v + "~~"
+	case d.Success:
+		v = "**" + v + "**"
+	case d.Failure:
+		v = "_" + v + "_"
+	}
+	sb.WriteString(v)
+}
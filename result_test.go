@@ -0,0 +1,36 @@
+package dice
+
+import (
+	"testing"
+)
+
+type resultTestCase struct {
+	name     string
+	in       *Result
+	expected string
+}
+
+var literalTwo = &Result{Kind: LiteralResult, Expression: "2", Value: intValue(2)}
+var literalThree = &Result{Kind: LiteralResult, Expression: "3", Value: intValue(3)}
+
+var resultTestCases = []resultTestCase{
+	{"Literal renders its value", literalTwo, "2 = 2"},
+	{"Dice render their faces with dropped dice struck through", &Result{Kind: DiceResult, Expression: "4d6kh3", Value: intValue(14), Dice: []Die{{Value: 6}, {Value: 5}, {Value: 3}, {Value: 1, Dropped: true}}}, "4d6kh3 [6, 5, 3, ~~1~~] = 14"},
+	{"Dice render explosions, rerolls, successes and failures", &Result{Kind: DiceResult, Expression: "3d6!r1>=5f2", Value: intValue(1), Dice: []Die{{Value: 6, Exploded: true, Success: true}, {Value: 2, Rerolled: []int{1}, Failure: true}, {Value: 5, Success: true}}}, "3d6!r1>=5f2 [**6!**, ~~1~~ _2_, **5**] = 1"},
+	{"Operators render infix", &Result{Kind: OperatorResult, Expression: "+", Value: intValue(5), Children: []*Result{literalTwo, literalThree}}, "2 + 3 = 5"},
+	{"Lower precedence children are wrapped in parens", &Result{Kind: OperatorResult, Expression: "*", Value: intValue(10), Children: []*Result{{Kind: OperatorResult, Expression: "+", Value: intValue(5), Children: []*Result{literalTwo, literalThree}}, literalTwo}}, "(2 + 3) * 2 = 10"},
+	{"Right children of left-associative operators with the same precedence are wrapped in parens", &Result{Kind: OperatorResult, Expression: "-", Value: intValue(3), Children: []*Result{literalTwo, {Kind: OperatorResult, Expression: "-", Value: intValue(-1), Children: []*Result{literalTwo, literalThree}}}}, "2 - (2 - 3) = 3"},
+	{"Unary operators render prefix", &Result{Kind: UnaryResult, Expression: "-", Value: intValue(-5), Children: []*Result{{Kind: OperatorResult, Expression: "+", Value: intValue(5), Children: []*Result{literalTwo, literalThree}}}}, "-(2 + 3) = -5"},
+	{"Calls render their arguments", &Result{Kind: CallResult, Expression: "max", Value: intValue(3), Children: []*Result{literalTwo, literalThree}}, "max(2, 3) = 3"},
+	{"Conditionals render the condition and the picked branch", &Result{Kind: ConditionalResult, Expression: "?", Value: intValue(2), Children: []*Result{{Kind: OperatorResult, Expression: "<", Value: boolValue(true), Children: []*Result{literalTwo, literalThree}}, literalTwo}}, "2 < 3 ? 2 = 2"},
+}
+
+func TestResultString(t *testing.T) {
+	for _, tc := range resultTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.in.String() != tc.expected {
+				t.Fatalf("Expected %s but found %s.\n", tc.expected, tc.in.String())
+			}
+		})
+	}
+}
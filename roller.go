@@ -0,0 +1,50 @@
+package dice
+
+import (
+	crand "crypto/rand"
+	"math"
+	"math/rand/v2"
+)
+
+// Roller is the source of randomness used to roll dice. IntN returns a uniformly random int in [0, n) and is never called with n < 1.
+// A *rand.Rand from math/rand/v2 is a Roller.
+type Roller interface {
+	IntN(n int) int
+}
+
+// globalRoller rolls with the automatically seeded, goroutine safe, top-level functions of math/rand/v2
+type globalRoller struct{}
+
+func (globalRoller) IntN(n int) int {
+	return rand.IntN(n)
+}
+
+// NewSeededRoller returns a Roller that produces the same rolls every time it is created with the same seed.
+// It is not safe for concurrent use.
+func NewSeededRoller(seed uint64) Roller {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// CryptoRoller rolls with crypto/rand so rolls can't be predicted from the state of a PRNG. It is safe for concurrent use.
+type CryptoRoller struct{}
+
+// IntN returns a uniformly random int in [0, n). Random values from the incomplete block at the top of the
+// uint64 range are thrown away and drawn again, so no face is more likely than another.
+func (CryptoRoller) IntN(n int) int {
+	bound := uint64(n)
+	// 2^64 % bound, the number of values that would bias the result
+	rem := (math.MaxUint64%bound + 1) % bound
+
+	var buf [8]byte
+	for {
+		// crypto/rand.Read never returns an error
+		_, _ = crand.Read(buf[:])
+		var x uint64
+		for _, b := range buf {
+			x = x<<8 | uint64(b)
+		}
+		if rem == 0 || x < -rem {
+			return int(x % bound)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package dice
+
+import (
+	"math/rand/v2"
+	"testing"
+)
+
+// highRoller always rolls the highest face so tests can predict the result of dice terms
+type highRoller struct{}
+
+func (highRoller) IntN(n int) int {
+	return n - 1
+}
+
+func TestSeededRollerIsReproducible(t *testing.T) {
+	first, second := NewSeededRoller(42), NewSeededRoller(42)
+	for range 100 {
+		a, b := first.IntN(20), second.IntN(20)
+		if a != b {
+			t.Fatalf("Expected rollers with the same seed to roll the same but found %d and %d.\n", a, b)
+		}
+	}
+}
+
+func TestParseWithSeedIsReproducible(t *testing.T) {
+	input := []byte("10d20kh5+4dF+d{1,2,3}")
+	first := NewParser(input, WithSeed(7))
+	second := NewParser(input, WithRandSource(rand.NewPCG(7, 7)))
+
+	a, err := first.ParseResult()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	b, err := second.ParseResult()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if a.String() != b.String() {
+		t.Fatalf("Expected the same seed to give the same rolls but found %s and %s.\n", a, b)
+	}
+}
+
+func TestGlobalRollerStaysInRange(t *testing.T) {
+	for range 100 {
+		if n := (globalRoller{}).IntN(6); n < 0 || n >= 6 {
+			t.Fatalf("Expected roll in [0, 6) but found %d.\n", n)
+		}
+	}
+}
+
+func TestCryptoRollerIsUnbiased(t *testing.T) {
+	if n := (CryptoRoller{}).IntN(1); n != 0 {
+		t.Fatalf("Expected a 1 sided roll to be 0 but found %d.\n", n)
+	}
+
+	const faces, rolls = 6, 60000
+	counts := make([]int, faces)
+	for range rolls {
+		n := (CryptoRoller{}).IntN(faces)
+		if n < 0 || n >= faces {
+			t.Fatalf("Expected roll in [0, %d) but found %d.\n", faces, n)
+		}
+		counts[n]++
+	}
+
+	// every face is expected 10000 times, 5 standard deviations is roughly 456
+	for face, count := range counts {
+		if count < 9500 || count > 10500 {
+			t.Fatalf("Face %d was rolled %d times out of %d which is far from uniform.\n", face, count, rolls)
+		}
+	}
+}
+
+func TestParseWithCryptoRoller(t *testing.T) {
+	p := NewParser([]byte("4d6kh3"), WithRoller(CryptoRoller{}))
+	res, err := p.Parse()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	if res < 3 || res > 18 {
+		t.Fatalf("Expected result between 3 and 18 but found %d.\n", res)
+	}
+}
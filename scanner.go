@@ -1,7 +1,9 @@
 package dice
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 )
 
 type scanner struct {
@@ -28,6 +30,14 @@ func (scanner *scanner) readByte() byte {
 	return eofByte
 }
 
+// peekSecondByte returns the byte after the one at currentPos without advancing the cursor
+func (scanner *scanner) peekSecondByte() byte {
+	if (scanner.currentPos + 1) < len(scanner.buffer) {
+		return scanner.buffer[scanner.currentPos+1]
+	}
+	return eofByte
+}
+
 // functions
 func isWhiteSpace(b byte) bool {
 	return b == ' ' || b == '\n' || b == '\r' || b == '\v' || b == '\t'
@@ -41,13 +51,314 @@ func isDiceCharacter(b byte) bool {
 	return b == 'd' || b == 'D'
 }
 
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || isUpperCaseLetter(b)
+}
+
+func isUpperCaseLetter(b byte) bool {
+	return b >= 'A' && b <= 'Z'
+}
+
+func isCompareCharacter(b byte) bool {
+	return b == '<' || b == '>' || b == '='
+}
+
 func isOperator(b byte) bool {
-	return b == '+' || b == '-' || b == '*' || b == '/' || b == '(' || b == ')'
+	return b == '+' || b == '-' || b == '*' || b == '/' || b == '%' || b == '^' || b == '(' || b == ')' || b == ',' ||
+		b == '<' || b == '>' || b == '=' || b == '!' || b == '&' || b == '|' || b == '?' || b == ':'
+}
+
+// operators that are two bytes long. A byte that starts one of these is read together with the byte after it when they match.
+var twoByteOperators = map[string]bool{
+	"**": true,
+	"<=": true,
+	">=": true,
+	"==": true,
+	"!=": true,
+	"&&": true,
+	"||": true,
 }
 
 // limit the bytes to a subset
 func isValidByte(b byte) bool {
-	return isWhiteSpace(b) || isDigit(b) || isDiceCharacter(b) || isOperator(b) || b == eofByte
+	return isWhiteSpace(b) || isDigit(b) || isLetter(b) || isOperator(b) || b == eofByte
+}
+
+// describeByte returns a byte for an error message, naming the end of the buffer instead of printing it
+func describeByte(b byte) string {
+	if b == eofByte {
+		return eof.String()
+	}
+	return string(b)
+}
+
+// readInt reads a run of digits starting at currentPos and converts it to an int
+func (scanner *scanner) readInt() (int, error) {
+	start := scanner.currentPos
+	for isDigit(scanner.peekByte()) {
+		_ = scanner.readByte()
+	}
+	digits := string(scanner.buffer[start:scanner.currentPos])
+	n, err := strconv.Atoi(digits)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, errorf(ErrOverflow, "Number %s is too large.", digits)
+	}
+	return n, err
+}
+
+// readDiceTerm reads a dice term of the form [count](d|D)(faces|F|%|{faces,...}|NAME)[modifiers] starting at currentPos
+func (scanner *scanner) readDiceTerm() (diceTerm, error) {
+	var err error
+	term := diceTerm{count: 1}
+
+	if isDigit(scanner.peekByte()) {
+		term.count, err = scanner.readInt()
+		if err != nil {
+			return diceTerm{}, err
+		}
+	}
+
+	b := scanner.readByte()
+	if !isDiceCharacter(b) {
+		return diceTerm{}, fmt.Errorf("Expected d/D in dice term. Found %s", describeByte(b))
+	}
+
+	// check if byte after d/D is a digit or one of the special dice kinds
+	switch p := scanner.peekByte(); {
+	case isUpperCaseLetter(p):
+		start := scanner.currentPos
+		for isUpperCaseLetter(scanner.peekByte()) {
+			_ = scanner.readByte()
+		}
+
+		name := string(scanner.buffer[start:scanner.currentPos])
+		if name == "F" {
+			term.kind = fateDie
+			term.faces = 3
+			break
+		}
+
+		values, ok := lookupDie(name)
+		if !ok {
+			return diceTerm{}, fmt.Errorf("Unknown die d%s. Named dice must be registered with RegisterDie.", name)
+		}
+		term.kind = customDie
+		term.faces = len(values)
+		term.values = values
+	case p == '{':
+		term.values, err = scanner.readFaceList()
+		if err != nil {
+			return diceTerm{}, err
+		}
+		term.kind = customDie
+		term.faces = len(term.values)
+	case p == '%':
+		_ = scanner.readByte()
+		term.kind = percentileDie
+		term.faces = 100
+	case isDigit(p):
+		term.faces, err = scanner.readInt()
+		if err != nil {
+			return diceTerm{}, err
+		}
+	default:
+		return diceTerm{}, fmt.Errorf("Character after d/D not a digit, {, %% or die name. Found %s", describeByte(p))
+	}
+
+	for {
+		switch scanner.peekByte() {
+		case 'k', 'd', 'D':
+			if term.selection != selectAll {
+				return diceTerm{}, fmt.Errorf("Dice term can only have one keep or drop modifier.")
+			}
+			term.selection, err = scanner.readSelectMode()
+			if err != nil {
+				return diceTerm{}, err
+			}
+
+			// the number of dice to keep or drop defaults to 1 when omitted
+			term.selectCount = 1
+			if isDigit(scanner.peekByte()) {
+				term.selectCount, err = scanner.readInt()
+				if err != nil {
+					return diceTerm{}, err
+				}
+			}
+		case '!':
+			// != ends the dice term like ==, so 1d20!=20 compares the roll instead of exploding on 20
+			if scanner.peekSecondByte() == '=' {
+				return term, term.validate()
+			}
+			if term.explode != explodeNone {
+				return diceTerm{}, fmt.Errorf("Dice term can only have one explode modifier.")
+			}
+			_ = scanner.readByte()
+
+			term.explode = explodeStandard
+			switch scanner.peekByte() {
+			case '!':
+				_ = scanner.readByte()
+				term.explode = explodeCompound
+			case 'p':
+				_ = scanner.readByte()
+				term.explode = explodePenetrate
+			}
+
+			// dice explode on their highest face unless given a compare point
+			term.explodeAt = comparePoint{compareEqual, term.maxFace()}
+			if p := scanner.peekByte(); isDigit(p) || isCompareCharacter(p) {
+				term.explodeAt, err = scanner.readComparePoint()
+				if err != nil {
+					return diceTerm{}, err
+				}
+			}
+		case 'r':
+			if term.reroll != rerollNone {
+				return diceTerm{}, fmt.Errorf("Dice term can only have one reroll modifier.")
+			}
+			_ = scanner.readByte()
+
+			term.reroll = rerollAlways
+			if scanner.peekByte() == 'o' {
+				_ = scanner.readByte()
+				term.reroll = rerollOnce
+			}
+
+			// dice reroll 1s unless given a compare point
+			term.rerollAt = comparePoint{compareEqual, 1}
+			if p := scanner.peekByte(); isDigit(p) || isCompareCharacter(p) {
+				term.rerollAt, err = scanner.readComparePoint()
+				if err != nil {
+					return diceTerm{}, err
+				}
+			}
+		case '<', '>', '=':
+			// == ends the dice term so 1d6==3 compares the roll instead of counting successes
+			if scanner.peekByte() == '=' && scanner.peekSecondByte() == '=' {
+				return term, term.validate()
+			}
+			if term.countSuccesses {
+				return diceTerm{}, fmt.Errorf("Dice term can only have one success compare point.")
+			}
+			term.countSuccesses = true
+			term.successAt, err = scanner.readComparePoint()
+			if err != nil {
+				return diceTerm{}, err
+			}
+		case 'f':
+			if term.countFailures {
+				return diceTerm{}, fmt.Errorf("Dice term can only have one failure compare point.")
+			}
+			_ = scanner.readByte()
+			term.countFailures = true
+			term.failureAt, err = scanner.readComparePoint()
+			if err != nil {
+				return diceTerm{}, err
+			}
+		default:
+			return term, term.validate()
+		}
+	}
+}
+
+// readFaceList reads a list of faces such as {1,1,2,3,5,8} starting at currentPos
+func (scanner *scanner) readFaceList() ([]int, error) {
+	_ = scanner.readByte()
+	faces := []int{}
+	for {
+		negative := scanner.peekByte() == '-'
+		if negative {
+			_ = scanner.readByte()
+		}
+
+		if p := scanner.peekByte(); !isDigit(p) {
+			return nil, fmt.Errorf("Expected a number in face list. Found %s", describeByte(p))
+		}
+		face, err := scanner.readInt()
+		if err != nil {
+			return nil, err
+		}
+		if negative {
+			face = -face
+		}
+		faces = append(faces, face)
+
+		switch b := scanner.readByte(); b {
+		case ',':
+		case '}':
+			return faces, nil
+		default:
+			return nil, fmt.Errorf("Expected , or } in face list. Found %s", describeByte(b))
+		}
+	}
+}
+
+// readComparePoint reads a compare point such as >=5 starting at currentPos. A bare number means =.
+func (scanner *scanner) readComparePoint() (comparePoint, error) {
+	cp := comparePoint{op: compareEqual}
+	opStart := scanner.currentPos
+	switch scanner.peekByte() {
+	case '=':
+		_ = scanner.readByte()
+	case '<':
+		_ = scanner.readByte()
+		cp.op = compareLess
+		if scanner.peekByte() == '=' {
+			_ = scanner.readByte()
+			cp.op = compareLessEqual
+		}
+	case '>':
+		_ = scanner.readByte()
+		cp.op = compareGreater
+		if scanner.peekByte() == '=' {
+			_ = scanner.readByte()
+			cp.op = compareGreaterEqual
+		}
+	}
+
+	if p := scanner.peekByte(); !isDigit(p) {
+		return comparePoint{}, fmt.Errorf("Compare point must end with a number. Found %s", describeByte(p))
+	}
+
+	start := scanner.currentPos
+	var err error
+	cp.value, err = scanner.readInt()
+	if err != nil {
+		return comparePoint{}, err
+	}
+
+	// dice straight after a compare point were meant to be compared with, but keep and drop modifiers can follow
+	op := string(scanner.buffer[opStart:start])
+	if op != "" && isDiceCharacter(scanner.peekByte()) && scanner.peekSecondByte() != 'h' && scanner.peekSecondByte() != 'l' {
+		return comparePoint{}, fmt.Errorf("Compare point %s%d written straight after dice can't be followed by more dice. Put spaces around %s to compare two rolls.", op, cp.value, op)
+	}
+	return cp, nil
+}
+
+// readSelectMode reads a kh, kl, dh or dl modifier starting at currentPos
+func (scanner *scanner) readSelectMode() (selectMode, error) {
+	m := scanner.readByte()
+	b := scanner.readByte()
+	if isDiceCharacter(m) && b == eofByte {
+		return selectAll, fmt.Errorf("Ambiguous %c at the end of dice term. Use dh or dl to drop dice.", m)
+	}
+	if isDiceCharacter(m) && b != 'h' && b != 'l' {
+		// 4d6d1 could be a second dice term or a drop modifier, so make the user pick
+		return selectAll, fmt.Errorf("Ambiguous %c%c in dice term. Use dh or dl to drop dice.", m, b)
+	}
+
+	switch {
+	case m == 'k' && b == 'h':
+		return keepHighest, nil
+	case m == 'k' && b == 'l':
+		return keepLowest, nil
+	case b == 'h':
+		return dropHighest, nil
+	case b == 'l':
+		return dropLowest, nil
+	default:
+		return selectAll, fmt.Errorf("Character after k must be h or l. Found %s", describeByte(b))
+	}
 }
 
 // reads the next token from the scanner's bufio.reader
@@ -62,62 +373,65 @@ func (scanner *scanner) readToken() (token, error) {
 	}
 
 	if !isValidByte(b) {
-		return token{}, fmt.Errorf("Invalid byte (%c) found in buffer.", b)
+		return token{}, newSyntaxError(scanner.buffer, scanner.startPos, scanner.currentPos, "", "byte", "Invalid byte (%c) found in buffer.", b)
 	}
 
 	if b == eofByte {
-		t := token{eof, string(scanner.buffer[scanner.startPos:scanner.currentPos])}
-		scanner.startPos = scanner.currentPos
-		return t, nil
+		return scanner.emit(eof), nil
 	}
 
 	if isOperator(b) {
-		t := token{operator, string(scanner.buffer[scanner.startPos:scanner.currentPos])}
-		scanner.startPos = scanner.currentPos
-		return t, nil
-	}
-
-	isDiceExp := false
-	if isDiceCharacter(b) {
-		isDiceExp = true
+		if twoByteOperators[string([]byte{b, scanner.peekByte()})] {
+			_ = scanner.readByte()
+		} else if b == '=' || b == '&' || b == '|' {
+			err := errorf(ErrUnknownOperator, "Invalid operator %c. Did you mean %c%c?", b, b, b)
+			return token{}, wrapSyntaxError(scanner.buffer, scanner.startPos, scanner.currentPos, fmt.Sprintf("%c%c", b, b), operator.String(), err)
+		}
+		return scanner.emit(operator), nil
 	}
 
-	// peek bytes 1 by 1, checking type and, when appropriate, adding to string builder by actually reading and then peeking the next byte
-	p := scanner.peekByte()
-	for {
-		if isDigit(p) {
+	// a term is a dice term if a d/D follows its leading digits, otherwise it is a literal.
+	// A term starting with any other letter is an identifier such as a function name.
+	kind := literal
+	if isLetter(b) && !isDiceCharacter(b) {
+		kind = identifier
+		for isLetter(scanner.peekByte()) {
 			_ = scanner.readByte()
-			p = scanner.peekByte()
-		} else if isDiceCharacter(p) {
-
-			// NOTE this isn't needed.
-			//if isDiceExp {
-			//	return token{}, scannerError{"Multiple d/D characters found in dice expression.", scanner.currentPos}
-			//}
-
-			isDiceExp = true
-
-			b = scanner.readByte()
-			// if b == 'D' {
-			//   b = 'd'
-			// }
-			// check if byte after d/D is a digit
-			p = scanner.peekByte()
-			if !isDigit(p) {
-				return token{}, fmt.Errorf("Character after d/D not a digit. Found %c", p)
-			}
-		} else if isWhiteSpace(p) || isOperator(p) || p == eofByte {
-			break
-		} else {
-			return token{}, fmt.Errorf("Invalid byte (%c) found in token.", p)
+		}
+	}
+	for kind == literal && isDigit(scanner.peekByte()) {
+		_ = scanner.readByte()
+	}
+	if kind == literal && (isDiceCharacter(b) || isDiceCharacter(scanner.peekByte())) {
+		kind = dice
+		scanner.currentPos = scanner.startPos
+		if _, err := scanner.readDiceTerm(); err != nil {
+			return token{}, wrapSyntaxError(scanner.buffer, scanner.startPos, max(scanner.currentPos, scanner.startPos+1), "", dice.String(), err)
 		}
 	}
 
-	// TODO clean this up. Default and then check and change isn't great.
-	t := token{literal, string(scanner.buffer[scanner.startPos:scanner.currentPos])}
-	if isDiceExp {
-		t.kind = dice
+	if p := scanner.peekByte(); !isWhiteSpace(p) && !isOperator(p) && p != eofByte {
+		return token{}, newSyntaxError(scanner.buffer, scanner.currentPos, scanner.currentPos+1, "whitespace, operator or EOF", "byte", "Invalid byte (%c) found in token.", p)
 	}
+
+	return scanner.emit(kind), nil
+}
+
+// skipInvalid moves past the text a readToken error was found in and returns it as an invalid token, so a parser
+// that recovers from syntax errors can scan the rest of the buffer. The rest of a bad word is skipped with it, while
+// a bad operator is skipped on its own.
+func (scanner *scanner) skipInvalid(err *SyntaxError) token {
+	end := min(max(err.End, scanner.currentPos, scanner.startPos+1), len(scanner.buffer))
+	for err.Found != operator.String() && end < len(scanner.buffer) && !isWhiteSpace(scanner.buffer[end]) && !isOperator(scanner.buffer[end]) {
+		end++
+	}
+	scanner.currentPos = end
+	return scanner.emit(invalid)
+}
+
+// emit returns a token of the given kind for the bytes from startPos to currentPos and starts the next token
+func (scanner *scanner) emit(kind tokenType) token {
+	t := token{kind, string(scanner.buffer[scanner.startPos:scanner.currentPos]), scanner.startPos, scanner.currentPos}
 	scanner.startPos = scanner.currentPos
-	return t, nil
+	return t
 }
@@ -21,25 +21,43 @@ var invalidScannerTestCases = []scannerTestCase{
 	{"Multiple d/D in same expression", "1dd2+3", nil, errors.New("Multiple d/D in the same expression at poisiton 2")},
 	{"Invalid characters in input string", "1c2+3", nil, errors.New("Unknown/invalid character (c) found at position 1")},
 	{"Invalid characters at start of a term", "c2", nil, errors.New("Unknown/invalid character (c) found at position 1")},
+	{"Keep modifier without h or l", "4d6k3", nil, errors.New("Character after k must be h or l. Found 3")},
+	{"Multiple keep modifiers in the same term", "4d6kh3kl1", nil, errors.New("Dice term can only have one keep or drop modifier.")},
+	{"Compare point without a number", "d6!>+1", nil, errors.New("Compare point must end with a number. Found +")},
+	{"Single & is not an operator", "1 & 2", nil, errors.New("Invalid operator &. Did you mean &&?")},
+	{"Second d/D without h or l is ambiguous", "4d6d1", nil, errors.New("Ambiguous d1 in dice term. Use dh or dl to drop dice.")},
+	{"Dice straight after a success compare point", "2d6>1d10", nil, errors.New("Compare point >1 written straight after dice can't be followed by more dice. Put spaces around > to compare two rolls.")},
 }
 
 var validScannerTestCases = []scannerTestCase{
-	{"Only a number is a valid token", "10", []token{{literal, "10"}, {eof, ""}}, nil},
-	{"Only a dice expression that has the pattern XdY is a valid token", "1d6", []token{{dice, "1d6"}, {eof, ""}}, nil},
-	{"Only a dice expression that has the pattern dY is a valid token", "d6", []token{{dice, "d6"}, {eof, ""}}, nil},
-	{"Input has '(' and ')' around any number of terms", "(d6+1)*2", []token{{operator, "("}, {dice, "d6"}, {operator, "+"}, {literal, "1"}, {operator, ")"}, {operator, "*"}, {literal, "2"}, {eof, ""}}, nil},
-	{"Input has many '(' and ')' around any number of terms", "((d6+1)*2)+(2d12/2)", []token{{operator, "("}, {operator, "("}, {dice, "d6"}, {operator, "+"}, {literal, "1"}, {operator, ")"}, {operator, "*"}, {literal, "2"}, {operator, ")"}, {operator, "+"}, {operator, "("}, {dice, "2d12"}, {operator, "/"}, {literal, "2"}, {operator, ")"}, {eof, ""}}, nil},
-
-	{"Input has many whitespace characters and terms", "(     d6\n+\v    \r1)   *\t2", []token{{operator, "("}, {dice, "d6"}, {operator, "+"}, {literal, "1"}, {operator, ")"}, {operator, "*"}, {literal, "2"}, {eof, ""}}, nil},
+	{"Only a number is a valid token", "10", []token{{literal, "10", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Only a dice expression that has the pattern XdY is a valid token", "1d6", []token{{dice, "1d6", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Only a dice expression that has the pattern dY is a valid token", "d6", []token{{dice, "d6", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Dice expression with a keep highest modifier is a valid token", "4d6kh3", []token{{dice, "4d6kh3", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Dice expression with a keep lowest modifier and no keep count is a valid token", "2d20kl+1", []token{{dice, "2d20kl", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Dice expression with a drop lowest modifier is a valid token", "4d6dl1", []token{{dice, "4d6dl1", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Dice expression with an explode modifier and compare point is a valid token", "d6!>=5+2", []token{{dice, "d6!>=5", 0, 0}, {operator, "+", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Dice expression with a reroll once modifier is a valid token", "1d20ro1", []token{{dice, "1d20ro1", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Fate and percentile dice expressions are valid tokens", "4dF+d%", []token{{dice, "4dF", 0, 0}, {operator, "+", 0, 0}, {dice, "d%", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Custom dice expressions are valid tokens", "2d{1,-1,0}kh1", []token{{dice, "2d{1,-1,0}kh1", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Input has '(' and ')' around any number of terms", "(d6+1)*2", []token{{operator, "(", 0, 0}, {dice, "d6", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {operator, ")", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Input has many '(' and ')' around any number of terms", "((d6+1)*2)+(2d12/2)", []token{{operator, "(", 0, 0}, {operator, "(", 0, 0}, {dice, "d6", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {operator, ")", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {operator, "+", 0, 0}, {operator, "(", 0, 0}, {dice, "2d12", 0, 0}, {operator, "/", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, nil},
+
+	{"Input has many whitespace characters and terms", "(     d6\n+\v    \r1)   *\t2", []token{{operator, "(", 0, 0}, {dice, "d6", 0, 0}, {operator, "+", 0, 0}, {literal, "1", 0, 0}, {operator, ")", 0, 0}, {operator, "*", 0, 0}, {literal, "2", 0, 0}, {eof, "", 0, 0}}, nil},
 	// below are valid input strings for the tokenize method but aren't valid in the lexer.
-	{"Only an operator is a valid token", "-", []token{{operator, "-"}, {eof, ""}}, nil},
-	{"Only an operator is a valid token", "-*/", []token{{operator, "-"}, {operator, "*"}, {operator, "/"}, {eof, ""}}, nil},
-	{"Empty string produces only EOF token", "", []token{{eof, ""}}, nil},
-	{"Contains only valid literals, dice expressions, and operators in any order", "+1d4/", []token{{operator, "+"}, {dice, "1d4"}, {operator, "/"}, {eof, ""}}, nil},
+	{"Only an operator is a valid token", "-", []token{{operator, "-", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Only an operator is a valid token", "-*/", []token{{operator, "-", 0, 0}, {operator, "*", 0, 0}, {operator, "/", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Two * in a row is a single ** operator token", "2**3%4", []token{{literal, "2", 0, 0}, {operator, "**", 0, 0}, {literal, "3", 0, 0}, {operator, "%", 0, 0}, {literal, "4", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Function names are identifier tokens", "max(d6, 2)", []token{{identifier, "max", 0, 0}, {operator, "(", 0, 0}, {dice, "d6", 0, 0}, {operator, ",", 0, 0}, {literal, "2", 0, 0}, {operator, ")", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Comparison, logical and conditional operators are operator tokens", "1<=2&&!(3!=4)||5>6?7:8", []token{{literal, "1", 0, 0}, {operator, "<=", 0, 0}, {literal, "2", 0, 0}, {operator, "&&", 0, 0}, {operator, "!", 0, 0}, {operator, "(", 0, 0}, {literal, "3", 0, 0}, {operator, "!=", 0, 0}, {literal, "4", 0, 0}, {operator, ")", 0, 0}, {operator, "||", 0, 0}, {literal, "5", 0, 0}, {operator, ">", 0, 0}, {literal, "6", 0, 0}, {operator, "?", 0, 0}, {literal, "7", 0, 0}, {operator, ":", 0, 0}, {literal, "8", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"== after a dice term is an operator rather than a compare point", "1d6==3", []token{{dice, "1d6", 0, 0}, {operator, "==", 0, 0}, {literal, "3", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"!= after a dice term is an operator rather than an explode modifier", "1d6!=3", []token{{dice, "1d6", 0, 0}, {operator, "!=", 0, 0}, {literal, "3", 0, 0}, {eof, "", 0, 0}}, nil},
+	{"Empty string produces only EOF token", "", []token{{eof, "", 0, 0}}, nil},
+	{"Contains only valid literals, dice expressions, and operators in any order", "+1d4/", []token{{operator, "+", 0, 0}, {dice, "1d4", 0, 0}, {operator, "/", 0, 0}, {eof, "", 0, 0}}, nil},
 
 	// TODO
-	//{"Converts 'D' in dice expression to lowercase when D is the first character", "D6", []token{{dice, "d6"}, {eof, ""}}, nil},
-	//{"Converts 'D' in dice expression to lowercase when D in middle of token", "1D6", []token{{dice, "1d6"}, {eof, ""}}, nil},
+	//{"Converts 'D' in dice expression to lowercase when D is the first character", "D6", []token{{dice, "d6", 0, 0}, {eof, "", 0, 0}}, nil},
+	//{"Converts 'D' in dice expression to lowercase when D in middle of token", "1D6", []token{{dice, "1d6", 0, 0}, {eof, "", 0, 0}}, nil},
 }
 
 func TestScannerWithInvalidInputString(t *testing.T) {
@@ -59,6 +77,31 @@ func TestScannerWithInvalidInputString(t *testing.T) {
 	}
 }
 
+var endOfInputTestCases = []scannerTestCase{
+	{"Keep modifier at end of input", "1d6k", nil, errors.New("Character after k must be h or l. Found EOF at offset 0.")},
+	{"Second d/D at end of input", "4d6d", nil, errors.New("Ambiguous d at the end of dice term. Use dh or dl to drop dice at offset 0.")},
+	{"Missing number after d at end of input", "1d", nil, errors.New("Character after d/D not a digit, {, % or die name. Found EOF at offset 0.")},
+	{"Compare point at end of input", "d6!>", nil, errors.New("Compare point must end with a number. Found EOF at offset 0.")},
+	{"Face list after a comma at end of input", "d{1,", nil, errors.New("Expected a number in face list. Found EOF at offset 0.")},
+	{"Face list after a number at end of input", "d{1", nil, errors.New("Expected , or } in face list. Found EOF at offset 0.")},
+}
+
+func TestScannerNamesTheEndOfInput(t *testing.T) {
+	for _, tc := range endOfInputTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := scanner{[]byte(tc.input), 0, 0}
+			_, err := s.readToken()
+			for err == nil {
+				_, err = s.readToken()
+			}
+
+			if err.Error() != tc.expectedError.Error() {
+				t.Fatalf("Expected error %q but found %q.\n", tc.expectedError.Error(), err.Error())
+			}
+		})
+	}
+}
+
 func TestScannerWithValidInputString(t *testing.T) {
 	for _, tc := range validScannerTestCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -157,7 +200,7 @@ type byteFunctionTestCase struct {
 var whitespaceBytes = []byte{' ', '\n', '\r', '\v', '\t'}
 var digitBytes = []byte{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9'}
 var diceCharacterBytes = []byte{'d', 'D'}
-var operatorBytes = []byte{'+', '-', '*', '/', '(', ')'}
+var operatorBytes = []byte{'+', '-', '*', '/', '%', '^', '(', ')', ',', '<', '>', '=', '!', '&', '|', '?', ':'}
 
 func getRandomByteOutsideSet(excludes []byte) byte {
 	randomByte := byte(rand.IntN(128))
@@ -232,3 +275,18 @@ func TestScannerIsOperator(t *testing.T) {
 		})
 	}
 }
+
+func TestScannerTokenOffsets(t *testing.T) {
+	s := scanner{[]byte("  4d6kh3 >=\t10"), 0, 0}
+	expected := []token{{dice, "4d6kh3", 2, 8}, {operator, ">=", 9, 11}, {literal, "10", 12, 14}, {eof, "", 14, 14}}
+
+	for idx, expectedToken := range expected {
+		tkn, err := s.readToken()
+		if err != nil {
+			t.Fatalf("Expected no error but found error with message %s.\n", err.Error())
+		}
+		if tkn != expectedToken {
+			t.Fatalf("Expected token at index %d to be %v but found %v.\n", idx, expectedToken, tkn)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package dice
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"sync"
+)
+
+// confidenceZ is the z-score of the 95% confidence intervals of a Simulation
+const confidenceZ = 1.959964
+
+// progressInterval is how many runs a worker finishes between progress reports
+const progressInterval = 1000
+
+// Interval is a confidence interval of an estimated number
+type Interval struct {
+	Low  float64
+	High float64
+}
+
+// Simulation is the result of rolling an expression many times
+type Simulation struct {
+	Runs int
+	// Counts is how many runs rolled each value
+	Counts map[Value]int
+	// Distribution is the empirical histogram, the share of runs that rolled each value, sorted like an exact Distribution
+	Distribution Distribution
+	// Mean, StdDev and MeanInterval, the 95% confidence interval of the mean, are 0 when a run rolled a boolean
+	Mean         float64
+	StdDev       float64
+	MeanInterval Interval
+}
+
+// ProbabilityInterval returns the 95% Wilson score interval of the probability of rolling v
+func (simulation *Simulation) ProbabilityInterval(v Value) Interval {
+	n := float64(simulation.Runs)
+	p := float64(simulation.Counts[v]) / n
+	z2 := confidenceZ * confidenceZ
+
+	denominator := 1 + z2/n
+	center := (p + z2/(2*n)) / denominator
+	half := confidenceZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / denominator
+	return Interval{max(0, center-half), min(1, center+half)}
+}
+
+// simulationWorker is what a single worker of Simulate rolled
+type simulationWorker struct {
+	counts map[Value]int
+	sum    float64
+	sumSq  float64
+	err    error
+}
+
+// Simulate rolls the expression runs times, split evenly across the workers set by WithWorkers, and returns the
+// empirical histogram of the values it rolled. Every worker rolls with its own Roller, see WithWorkerRollers and
+// WithSeed, so WithRoller is ignored. Simulate stops and returns an error when a roll fails or ctx is done.
+func (expression *Expression) Simulate(ctx context.Context, runs int, opts ...Option) (*Simulation, error) {
+	if runs < 1 {
+		return nil, errorf(ErrInvalidArgument, "Simulation needs at least 1 run. Found %d.", runs)
+	}
+	o := applyOptions(opts)
+	if o.workers < 1 {
+		return nil, errorf(ErrInvalidArgument, "Simulation needs at least 1 worker. Found %d.", o.workers)
+	}
+	workers := min(o.workers, runs)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// a single long run stops too once ctx is done
+	o.ctx = ctx
+
+	results := make([]simulationWorker, workers)
+	done := make(chan int, workers)
+	// the first error cancels the other workers, so their context errors don't hide it
+	var firstErr error
+	var once sync.Once
+	var wg sync.WaitGroup
+	for w := range workers {
+		workerOpts := o
+		if o.workerRoller != nil {
+			workerOpts.roller = o.workerRoller(w)
+		} else {
+			workerOpts.roller = NewSeededRoller(rand.Uint64())
+		}
+
+		// every worker has a fixed share of the runs, so seeded simulations don't depend on scheduling
+		share := runs / workers
+		if w < runs%workers {
+			share++
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[w] = expression.simulateWorker(ctx, share, workerOpts, done)
+			if err := results[w].err; err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	finished := 0
+	for n := range done {
+		finished += n
+		if o.progress != nil {
+			o.progress(finished, runs)
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return newSimulation(runs, results), nil
+}
+
+func (expression *Expression) simulateWorker(ctx context.Context, runs int, opts options, done chan<- int) simulationWorker {
+	worker := simulationWorker{counts: map[Value]int{}}
+	reported := 0
+	for i := range runs {
+		if err := ctx.Err(); err != nil {
+			worker.err = err
+			return worker
+		}
+
+		result, err := walk(expression.root, opts)
+		if err != nil {
+			worker.err = err
+			return worker
+		}
+		worker.counts[result.Value]++
+		if !result.Value.IsBool() {
+			v := float64(result.Value.Int())
+			worker.sum += v
+			worker.sumSq += v * v
+		}
+
+		if (i+1)%progressInterval == 0 || i+1 == runs {
+			done <- i + 1 - reported
+			reported = i + 1
+		}
+	}
+	return worker
+}
+
+func newSimulation(runs int, results []simulationWorker) *Simulation {
+	simulation := &Simulation{Runs: runs, Counts: map[Value]int{}}
+	var sum, sumSq float64
+	hasBool := false
+	for _, result := range results {
+		for v, n := range result.counts {
+			simulation.Counts[v] += n
+			hasBool = hasBool || v.IsBool()
+		}
+		sum += result.sum
+		sumSq += result.sumSq
+	}
+
+	dist := outcomes{}
+	for v, n := range simulation.Counts {
+		dist[v] = float64(n) / float64(runs)
+	}
+	simulation.Distribution = dist.sorted()
+
+	if !hasBool {
+		n := float64(runs)
+		simulation.Mean = sum / n
+		simulation.StdDev = math.Sqrt(max(0, sumSq/n-simulation.Mean*simulation.Mean))
+		half := confidenceZ * simulation.StdDev / math.Sqrt(n)
+		simulation.MeanInterval = Interval{simulation.Mean - half, simulation.Mean + half}
+	}
+	return simulation
+}
@@ -0,0 +1,145 @@
+package dice
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"testing"
+)
+
+func compileForSimulation(t *testing.T, input string) *Expression {
+	t.Helper()
+	expression, err := Compile([]byte(input))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	return expression
+}
+
+func TestSimulateMatchesExactDistribution(t *testing.T) {
+	expression := compileForSimulation(t, "2d6")
+	simulation, err := expression.Simulate(context.Background(), 100_000, WithSeed(1), WithWorkers(4))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if simulation.Runs != 100_000 {
+		t.Fatalf("Expected 100000 runs but found %d.\n", simulation.Runs)
+	}
+	if simulation.MeanInterval.Low > 7 || simulation.MeanInterval.High < 7 {
+		t.Fatalf("Expected mean interval to contain 7 but found [%f, %f].\n", simulation.MeanInterval.Low, simulation.MeanInterval.High)
+	}
+	if math.Abs(simulation.StdDev-math.Sqrt(35.0/6)) > 0.05 {
+		t.Fatalf("Expected standard deviation close to %f but found %f.\n", math.Sqrt(35.0/6), simulation.StdDev)
+	}
+
+	exact, err := expression.Distribution()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	if len(simulation.Distribution) != len(exact) {
+		t.Fatalf("Expected %d values in the histogram but found %d.\n", len(exact), len(simulation.Distribution))
+	}
+	for _, outcome := range exact {
+		interval := simulation.ProbabilityInterval(outcome.Value)
+		if interval.Low > outcome.Probability || interval.High < outcome.Probability {
+			t.Fatalf("Expected interval of %s to contain %f but found [%f, %f].\n", outcome.Value, outcome.Probability, interval.Low, interval.High)
+		}
+	}
+}
+
+func TestSimulateWithSeedIsReproducible(t *testing.T) {
+	expression := compileForSimulation(t, "4d6kh3 + 1d20 >= 25 ? 1 : 10d10!10>=8f1")
+	a, err := expression.Simulate(context.Background(), 10_000, WithSeed(42), WithWorkers(3))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+	b, err := expression.Simulate(context.Background(), 10_000, WithSeed(42), WithWorkers(3))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if !maps.Equal(a.Counts, b.Counts) {
+		t.Fatalf("Expected the same counts for the same seed but found %v and %v.\n", a.Counts, b.Counts)
+	}
+}
+
+func TestSimulateWithBooleanExpression(t *testing.T) {
+	expression := compileForSimulation(t, "1d20 >= 11")
+	simulation, err := expression.Simulate(context.Background(), 10_000, WithSeed(7))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	interval := simulation.ProbabilityInterval(boolValue(true))
+	if interval.Low > 0.5 || interval.High < 0.5 {
+		t.Fatalf("Expected interval to contain 0.5 but found [%f, %f].\n", interval.Low, interval.High)
+	}
+	if simulation.Mean != 0 {
+		t.Fatalf("Expected mean of a boolean expression to be 0 but found %f.\n", simulation.Mean)
+	}
+}
+
+func TestSimulateReportsProgress(t *testing.T) {
+	expression := compileForSimulation(t, "1d6")
+	last := 0
+	calls := 0
+	_, err := expression.Simulate(context.Background(), 10_500, WithWorkers(2), WithProgress(func(done int, total int) {
+		if done <= last || total != 10_500 {
+			t.Errorf("Expected progress to grow towards 10500 but found %d of %d after %d.\n", done, total, last)
+		}
+		last = done
+		calls++
+	}))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if last != 10_500 {
+		t.Fatalf("Expected the last progress to be 10500 but found %d.\n", last)
+	}
+	if calls < 10 {
+		t.Fatalf("Expected progress to be reported at least 10 times but found %d.\n", calls)
+	}
+}
+
+func TestSimulateWithWorkerRollers(t *testing.T) {
+	expression := compileForSimulation(t, "4d6kh3")
+	simulation, err := expression.Simulate(context.Background(), 100, WithWorkers(4), WithWorkerRollers(func(int) Roller {
+		return highRoller{}
+	}))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if simulation.Counts[intValue(18)] != 100 {
+		t.Fatalf("Expected every run to roll 18 but found %v.\n", simulation.Counts)
+	}
+}
+
+func TestSimulateStopsWhenContextIsDone(t *testing.T) {
+	expression := compileForSimulation(t, "1d6")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := expression.Simulate(ctx, 1_000_000)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled but found %v.\n", err)
+	}
+}
+
+func TestSimulateWithInvalidInput(t *testing.T) {
+	expression := compileForSimulation(t, "1d6/(1d2-1)")
+	if _, err := expression.Simulate(context.Background(), 10_000, WithSeed(1)); err == nil {
+		t.Fatalf("Expected error for a division by zero but found none.\n")
+	}
+
+	expression = compileForSimulation(t, "1d6")
+	if _, err := expression.Simulate(context.Background(), 0); err == nil {
+		t.Fatalf("Expected error for 0 runs but found none.\n")
+	}
+	if _, err := expression.Simulate(context.Background(), 10, WithWorkers(0)); err == nil {
+		t.Fatalf("Expected error for 0 workers but found none.\n")
+	}
+}
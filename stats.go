@@ -0,0 +1,71 @@
+package dice
+
+import "math"
+
+// Stats summarises the distribution of an integer expression
+type Stats struct {
+	Mean     float64
+	Variance float64
+	StdDev   float64
+	Min      int
+	Max      int
+	Median   int
+	// Distribution is the exact distribution the statistics were computed from
+	Distribution Distribution
+}
+
+// Stats analyses the expression like Distribution and summarises its distribution. Expressions whose analysis needs
+// more outcomes or combinations than the limits set by WithMaxOutcomes and WithMaxCombinations return an error.
+func (expression *Expression) Stats(opts ...Option) (Stats, error) {
+	distribution, err := expression.Distribution(opts...)
+	if err != nil {
+		return Stats{}, err
+	}
+	return distribution.Stats()
+}
+
+// Stats summarises the distribution. It returns an error when the distribution is empty or has boolean outcomes.
+func (distribution Distribution) Stats() (Stats, error) {
+	if len(distribution) == 0 {
+		return Stats{}, errorf(ErrInvalidArgument, "Cannot compute statistics of an empty distribution.")
+	}
+
+	stats := Stats{Distribution: distribution}
+	for _, outcome := range distribution {
+		if outcome.Value.IsBool() {
+			return Stats{}, errorf(ErrType, "Statistics need an integer expression but found outcome %s.", outcome.Value)
+		}
+		stats.Mean += float64(outcome.Value.Int()) * outcome.Probability
+	}
+
+	for _, outcome := range distribution {
+		d := float64(outcome.Value.Int()) - stats.Mean
+		stats.Variance += d * d * outcome.Probability
+	}
+	stats.StdDev = math.Sqrt(stats.Variance)
+
+	// outcomes are sorted, so the smallest and largest values are at the ends
+	stats.Min = distribution[0].Value.Int()
+	stats.Max = distribution[len(distribution)-1].Value.Int()
+	stats.Median, _ = stats.Percentile(50)
+	return stats, nil
+}
+
+// Percentile returns the smallest value that the expression rolls at or below with a probability of at least p percent.
+// p must be between 0 and 100.
+func (stats Stats) Percentile(p float64) (int, error) {
+	if p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, errorf(ErrInvalidArgument, "Percentile must be between 0 and 100. Found %g.", p)
+	}
+
+	// leave some room for rounding errors in the sum of the probabilities
+	target := p/100 - 1e-12
+	cumulative := 0.0
+	for _, outcome := range stats.Distribution {
+		cumulative += outcome.Probability
+		if cumulative >= target {
+			return outcome.Value.Int(), nil
+		}
+	}
+	return stats.Max, nil
+}
@@ -0,0 +1,122 @@
+package dice
+
+import (
+	"math"
+	"testing"
+)
+
+type statsTestCase struct {
+	input    string
+	mean     float64
+	stdDev   float64
+	min      int
+	max      int
+	median   int
+	expected map[float64]int
+}
+
+var validStatsTestCases = []statsTestCase{
+	{"1d6", 3.5, math.Sqrt(35.0 / 12), 1, 6, 3, map[float64]int{0: 1, 10: 1, 50: 3, 51: 4, 100: 6}},
+	{"2d6", 7, math.Sqrt(35.0 / 6), 2, 12, 7, map[float64]int{25: 5, 75: 9}},
+	{"1d20+5", 15.5, math.Sqrt(399.0 / 12), 6, 25, 15, map[float64]int{5: 6, 95: 24}},
+	{"4d6kh3", 15869.0 / 1296, 2.8468, 3, 18, 12, map[float64]int{}},
+	{"3", 3, 0, 3, 3, 3, map[float64]int{0: 3, 100: 3}},
+	{"1d6/2", 1.5, math.Sqrt(11.0 / 12), 0, 3, 1, map[float64]int{}},
+}
+
+func TestStatsWithValidInput(t *testing.T) {
+	for _, tc := range validStatsTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expression, err := Compile([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			stats, err := expression.Stats()
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			if math.Abs(stats.Mean-tc.mean) > 1e-9 {
+				t.Fatalf("Expected mean %f but found %f.\n", tc.mean, stats.Mean)
+			}
+			if math.Abs(stats.StdDev-tc.stdDev) > 1e-4 {
+				t.Fatalf("Expected standard deviation %f but found %f.\n", tc.stdDev, stats.StdDev)
+			}
+			if math.Abs(stats.Variance-stats.StdDev*stats.StdDev) > 1e-9 {
+				t.Fatalf("Expected variance to be the square of the standard deviation but found %f.\n", stats.Variance)
+			}
+			if stats.Min != tc.min || stats.Max != tc.max || stats.Median != tc.median {
+				t.Fatalf("Expected min %d, max %d and median %d but found %d, %d and %d.\n", tc.min, tc.max, tc.median, stats.Min, stats.Max, stats.Median)
+			}
+
+			for p, expected := range tc.expected {
+				found, err := stats.Percentile(p)
+				if err != nil {
+					t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+				}
+				if found != expected {
+					t.Fatalf("Expected percentile %g to be %d but found %d.\n", p, expected, found)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsWithBooleanExpression(t *testing.T) {
+	expression, err := Compile([]byte("1d20 >= 10"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	if _, err := expression.Stats(); err == nil {
+		t.Fatalf("Expected error but found none.\n")
+	}
+}
+
+func TestStatsWithInvalidPercentile(t *testing.T) {
+	expression, err := Compile([]byte("1d6"))
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	stats, err := expression.Stats()
+	if err != nil {
+		t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+	}
+
+	for _, p := range []float64{-1, 101, math.NaN()} {
+		if _, err := stats.Percentile(p); err == nil {
+			t.Fatalf("Expected error for percentile %g but found none.\n", p)
+		}
+	}
+}
+
+type statsLimitTestCase struct {
+	input string
+	opt   Option
+}
+
+var statsLimitTestCases = []statsLimitTestCase{
+	{"d1000000000", WithMaxOutcomes(100_000)},
+	{"1000000d1000", WithMaxOutcomes(100_000)},
+	{"100d100", WithMaxOutcomes(1000)},
+	{"2d6 * 2d6", WithMaxCombinations(100)},
+	{"max(1d50, 1d50, 1d50)", WithMaxCombinations(1000)},
+	{"50d20kh10", WithMaxCombinations(1000)},
+}
+
+func TestStatsRejectsExpressionsPastTheLimits(t *testing.T) {
+	for _, tc := range statsLimitTestCases {
+		t.Run(tc.input, func(t *testing.T) {
+			expression, err := Compile([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+
+			if _, err := expression.Stats(tc.opt); err == nil {
+				t.Fatalf("Expected error but found none.\n")
+			}
+		})
+	}
+}
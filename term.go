@@ -0,0 +1,349 @@
+package dice
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// selectMode decides which of the rolled dice count towards the total
+type selectMode int
+
+const (
+	selectAll selectMode = iota
+	keepHighest
+	keepLowest
+	dropHighest
+	dropLowest
+)
+
+// dieKind decides which values the faces of a die show
+type dieKind int
+
+const (
+	numericDie    dieKind = iota // faces 1 to faces
+	fateDie                      // faces -1, 0 and +1
+	percentileDie                // d%, faces 1 to 100
+	customDie                    // faces listed inline or registered under a name
+)
+
+var (
+	namedDiceMutex sync.RWMutex
+	namedDice      = map[string][]int{}
+)
+
+// RegisterDie registers a die with the given faces so expressions can roll it as d{name}, e.g. dBOON.
+// Names are made of uppercase letters and F is reserved for Fate dice. Registering a name again replaces its faces.
+func RegisterDie(name string, faces []int) error {
+	if name == "" || name == "F" {
+		return errorf(ErrInvalidArgument, "Invalid die name %q.", name)
+	}
+	for i := range len(name) {
+		if !isUpperCaseLetter(name[i]) {
+			return errorf(ErrInvalidArgument, "Die name %q must only contain uppercase letters.", name)
+		}
+	}
+	if len(faces) == 0 {
+		return errorf(ErrInvalidArgument, "Die %s must have at least 1 face.", name)
+	}
+
+	namedDiceMutex.Lock()
+	defer namedDiceMutex.Unlock()
+	namedDice[name] = slices.Clone(faces)
+	return nil
+}
+
+// lookupDie returns the faces of a die registered with RegisterDie
+func lookupDie(name string) ([]int, bool) {
+	namedDiceMutex.RLock()
+	defer namedDiceMutex.RUnlock()
+	faces, ok := namedDice[name]
+	return faces, ok
+}
+
+// explodeMode decides what happens when a die lands on its explode compare point
+type explodeMode int
+
+const (
+	explodeNone      explodeMode = iota
+	explodeStandard              // roll another die and add it to the pool
+	explodeCompound              // roll again and add the result to the same die
+	explodePenetrate             // like explodeStandard but every extra die is reduced by 1
+)
+
+// rerollMode decides whether a die matching the reroll compare point is rolled again once or until it stops matching
+type rerollMode int
+
+const (
+	rerollNone rerollMode = iota
+	rerollAlways
+	rerollOnce
+)
+
+type compareOp int
+
+const (
+	compareEqual compareOp = iota
+	compareLess
+	compareLessEqual
+	compareGreater
+	compareGreaterEqual
+)
+
+// comparePoint is a condition such as >=5 that a single die can match
+type comparePoint struct {
+	op    compareOp
+	value int
+}
+
+func (cp comparePoint) matches(v int) bool {
+	switch cp.op {
+	case compareLess:
+		return v < cp.value
+	case compareLessEqual:
+		return v <= cp.value
+	case compareGreater:
+		return v > cp.value
+	case compareGreaterEqual:
+		return v >= cp.value
+	default:
+		return v == cp.value
+	}
+}
+
+// diceTerm is the parsed form of a dice token such as 4d6kh3
+type diceTerm struct {
+	count       int
+	kind        dieKind
+	faces       int
+	values      []int // the faces of a customDie
+	selection   selectMode
+	selectCount int
+	explode     explodeMode
+	explodeAt   comparePoint
+	reroll      rerollMode
+	rerollAt    comparePoint
+	// a term with a success compare point counts successes minus failures instead of summing faces
+	countSuccesses bool
+	successAt      comparePoint
+	countFailures  bool
+	failureAt      comparePoint
+}
+
+// Die is a single rolled die. Dropped dice are remembered but do not count towards the total.
+// Exploded dice caused another roll, which is either the next die or, when compounding, already added to Value.
+// Rerolled holds the faces that were thrown away by reroll modifiers, in the order they were rolled.
+// Success and Failure are set on the kept dice of a pool that matched its success or failure compare point.
+type Die struct {
+	Value    int
+	Dropped  bool
+	Exploded bool
+	Rerolled []int
+	Success  bool
+	Failure  bool
+}
+
+// PoolResult counts the kept dice of a term that has a success compare point
+type PoolResult struct {
+	Successes int
+	Failures  int
+}
+
+// Net is the value a pool contributes to arithmetic
+func (pool PoolResult) Net() int {
+	return pool.Successes - pool.Failures
+}
+
+// diceRoll is the outcome of rolling a diceTerm. Total is the sum of the kept dice or, for a pool, its net successes.
+type diceRoll struct {
+	dice  []Die
+	total int
+	pool  *PoolResult
+}
+
+// parseDiceTerm parses the value of a dice token into a diceTerm
+func parseDiceTerm(value string) (diceTerm, error) {
+	s := scanner{[]byte(value), 0, 0}
+	term, err := s.readDiceTerm()
+	if err != nil {
+		return diceTerm{}, err
+	}
+
+	if s.currentPos != len(s.buffer) {
+		return diceTerm{}, errorf(ErrSyntax, "Unexpected character (%c) in dice term %s.", s.buffer[s.currentPos], value)
+	}
+	return term, nil
+}
+
+// validate checks the modifiers of a term that can only be checked once the whole term has been read
+func (term diceTerm) validate() error {
+	if term.countFailures && !term.countSuccesses {
+		return errorf(ErrSyntax, "Failure compare point needs a success compare point in the same dice term.")
+	}
+	if term.faces < 1 {
+		return errorf(ErrSyntax, "Dice must have at least 1 face. Found %d.", term.faces)
+	}
+	if term.selection != selectAll && term.selectCount > term.count {
+		return errorf(ErrSyntax, "Cannot keep or drop %d dice from a roll of %d dice.", term.selectCount, term.count)
+	}
+	return nil
+}
+
+// face returns the value shown on the i-th face of the term's dice, counting from 0
+func (term diceTerm) face(i int) int {
+	switch term.kind {
+	case fateDie:
+		return i - 1
+	case customDie:
+		return term.values[i]
+	default:
+		return i + 1
+	}
+}
+
+// maxFace returns the highest value a single die of the term can show
+func (term diceTerm) maxFace() int {
+	if term.kind == customDie {
+		return slices.Max(term.values)
+	}
+	return term.face(term.faces - 1)
+}
+
+// rollFace rolls a single face, rerolling it according to the term's reroll modifier
+func (term diceTerm) rollFace(opts options) (int, []int, error) {
+	if err := opts.countDie(); err != nil {
+		return 0, nil, err
+	}
+	face := term.face(opts.roller.IntN(term.faces))
+	var rerolled []int
+	for term.reroll != rerollNone && term.rerollAt.matches(face) {
+		if term.reroll == rerollOnce && len(rerolled) == 1 {
+			break
+		}
+		if len(rerolled) == opts.maxRerolls {
+			return 0, nil, errorf(ErrTooManyRerolls, "Die was rerolled more than the limit of %d times.", opts.maxRerolls)
+		}
+		if err := opts.countDie(); err != nil {
+			return 0, nil, err
+		}
+
+		rerolled = append(rerolled, face)
+		face = term.face(opts.roller.IntN(term.faces))
+	}
+	return face, rerolled, nil
+}
+
+// checkLimits returns an error when the term has more dice or faces than opts allow
+func (term diceTerm) checkLimits(opts options) error {
+	if term.count > opts.maxDice {
+		return errorf(ErrTooManyDice, "Dice term rolls %d dice but the limit is %d.", term.count, opts.maxDice)
+	}
+	if term.faces > opts.maxFaces {
+		return errorf(ErrTooManyFaces, "Dice have %d faces but the limit is %d.", term.faces, opts.maxFaces)
+	}
+	return nil
+}
+
+func (term diceTerm) roll(opts options) (diceRoll, error) {
+	if err := term.validate(); err != nil {
+		return diceRoll{}, err
+	}
+	// check the limits before anything is allocated for the dice
+	if err := term.checkLimits(opts); err != nil {
+		return diceRoll{}, err
+	}
+	if opts.rolled == nil {
+		opts.rolled = new(int)
+	}
+
+	roll := diceRoll{dice: make([]Die, 0, term.count)}
+	for range term.count {
+		face, rerolled, err := term.rollFace(opts)
+		if err != nil {
+			return diceRoll{}, err
+		}
+
+		d := Die{Value: face, Rerolled: rerolled}
+		for explosions := 0; term.explode != explodeNone && term.explodeAt.matches(face); explosions++ {
+			if explosions == opts.maxExplosions {
+				return diceRoll{}, errorf(ErrTooManyExplosions, "Dice exploded more than the limit of %d times in a row.", opts.maxExplosions)
+			}
+
+			d.Exploded = true
+			face, rerolled, err = term.rollFace(opts)
+			if err != nil {
+				return diceRoll{}, err
+			}
+
+			if term.explode == explodeCompound {
+				if d.Value, err = add(d.Value, face); err != nil {
+					return diceRoll{}, err
+				}
+				d.Rerolled = append(d.Rerolled, rerolled...)
+				continue
+			}
+
+			roll.dice = append(roll.dice, d)
+			d = Die{Value: face, Rerolled: rerolled}
+			if term.explode == explodePenetrate {
+				if d.Value, err = add(d.Value, -1); err != nil {
+					return diceRoll{}, err
+				}
+			}
+		}
+		roll.dice = append(roll.dice, d)
+	}
+
+	if term.selection != selectAll {
+		// order indexes from the most to the least preferred die and drop everything past the kept dice
+		order := make([]int, len(roll.dice))
+		for i := range order {
+			order[i] = i
+		}
+		preferHighest := term.selection == keepHighest || term.selection == dropLowest
+		slices.SortStableFunc(order, func(a, b int) int {
+			if preferHighest {
+				return cmp.Compare(roll.dice[b].Value, roll.dice[a].Value)
+			}
+			return cmp.Compare(roll.dice[a].Value, roll.dice[b].Value)
+		})
+
+		kept := term.selectCount
+		if term.selection == dropHighest || term.selection == dropLowest {
+			kept = len(roll.dice) - term.selectCount
+		}
+		for _, i := range order[kept:] {
+			roll.dice[i].Dropped = true
+		}
+	}
+
+	if term.countSuccesses {
+		roll.pool = &PoolResult{}
+		for i, d := range roll.dice {
+			if d.Dropped {
+				continue
+			}
+			if term.successAt.matches(d.Value) {
+				roll.dice[i].Success = true
+				roll.pool.Successes++
+			} else if term.countFailures && term.failureAt.matches(d.Value) {
+				roll.dice[i].Failure = true
+				roll.pool.Failures++
+			}
+		}
+		roll.total = roll.pool.Net()
+		return roll, nil
+	}
+
+	for _, d := range roll.dice {
+		if d.Dropped {
+			continue
+		}
+		total, err := add(roll.total, d.Value)
+		if err != nil {
+			return diceRoll{}, err
+		}
+		roll.total = total
+	}
+	return roll, nil
+}
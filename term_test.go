@@ -0,0 +1,310 @@
+package dice
+
+import (
+	"reflect"
+	"testing"
+)
+
+type diceTermTestCase struct {
+	name     string
+	in       string
+	expected diceTerm
+}
+
+var invalidDiceTermTestCases = []diceTermTestCase{
+	{"Term without d/D returns error", "6", diceTerm{}},
+	{"Term without faces returns error", "2d", diceTerm{}},
+	{"Term with trailing characters returns error", "2d6x", diceTerm{}},
+	{"Term with unknown keep direction returns error", "4d6kx", diceTerm{}},
+	{"Term with lowercase fate dice returns error", "4df", diceTerm{}},
+	{"Term with empty face list returns error", "d{}", diceTerm{}},
+	{"Term with unclosed face list returns error", "d{1,2", diceTerm{}},
+	{"Term with unregistered named die returns error", "dNOTADIE", diceTerm{}},
+	{"Term with a bare second d is ambiguous and returns error", "4d6d1", diceTerm{}},
+	{"Term with both keep and drop modifiers returns error", "4d6kh3dl1", diceTerm{}},
+	{"Term with multiple explode modifiers returns error", "d6!!5!", diceTerm{}},
+	{"Term with compare point missing a number returns error", "d6!>=", diceTerm{}},
+	{"Term with multiple reroll modifiers returns error", "d6r1ro2", diceTerm{}},
+	{"Term with multiple success compare points returns error", "10d10>8>9", diceTerm{}},
+	{"Term with failure compare point but no success compare point returns error", "10d10f1", diceTerm{}},
+}
+
+var validDiceTermTestCases = []diceTermTestCase{
+	{"Term without count defaults count to 1", "d6", diceTerm{count: 1, faces: 6}},
+	{"Term with count and faces", "3D8", diceTerm{count: 3, faces: 8}},
+	{"Term with keep highest", "4d6kh3", diceTerm{count: 4, faces: 6, selection: keepHighest, selectCount: 3}},
+	{"Term with keep lowest and no keep count defaults keep count to 1", "2d20kl", diceTerm{count: 2, faces: 20, selection: keepLowest, selectCount: 1}},
+	{"Term with drop lowest", "4d6dl1", diceTerm{count: 4, faces: 6, selection: dropLowest, selectCount: 1}},
+	{"Term with drop highest", "5d10dh2", diceTerm{count: 5, faces: 10, selection: dropHighest, selectCount: 2}},
+	{"Term with explode defaults compare point to the highest face", "3d6!", diceTerm{count: 3, faces: 6, explode: explodeStandard, explodeAt: comparePoint{compareEqual, 6}}},
+	{"Term with compounding explode and compare point", "d6!!>=5", diceTerm{count: 1, faces: 6, explode: explodeCompound, explodeAt: comparePoint{compareGreaterEqual, 5}}},
+	{"Term with penetrating explode and bare compare point", "d10!p9", diceTerm{count: 1, faces: 10, explode: explodePenetrate, explodeAt: comparePoint{compareEqual, 9}}},
+	{"Term with reroll defaults compare point to 1", "2d6r", diceTerm{count: 2, faces: 6, reroll: rerollAlways, rerollAt: comparePoint{compareEqual, 1}}},
+	{"Term with reroll and compare point", "2d6r<2", diceTerm{count: 2, faces: 6, reroll: rerollAlways, rerollAt: comparePoint{compareLess, 2}}},
+	{"Term with reroll once and bare compare point", "1d20ro1", diceTerm{count: 1, faces: 20, reroll: rerollOnce, rerollAt: comparePoint{compareEqual, 1}}},
+	{"Term with success compare point", "10d10>=8", diceTerm{count: 10, faces: 10, countSuccesses: true, successAt: comparePoint{compareGreaterEqual, 8}}},
+	{"Term with success and failure compare points", "6d10>7f<2", diceTerm{count: 6, faces: 10, countSuccesses: true, successAt: comparePoint{compareGreater, 7}, countFailures: true, failureAt: comparePoint{compareLess, 2}}},
+	{"Term with fate dice", "4dF", diceTerm{count: 4, kind: fateDie, faces: 3}},
+	{"Term with fate dice explodes on +1 by default", "4dF!", diceTerm{count: 4, kind: fateDie, faces: 3, explode: explodeStandard, explodeAt: comparePoint{compareEqual, 1}}},
+	{"Term with percentile dice", "d%", diceTerm{count: 1, kind: percentileDie, faces: 100}},
+	{"Term with percentile dice and keep modifier", "2d%kl1", diceTerm{count: 2, kind: percentileDie, faces: 100, selection: keepLowest, selectCount: 1}},
+	{"Term with inline face list", "2d{1,1,2,3,5,8}", diceTerm{count: 2, kind: customDie, faces: 6, values: []int{1, 1, 2, 3, 5, 8}}},
+	{"Term with inline face list explodes on its highest face by default", "d{-1,5,2}!", diceTerm{count: 1, kind: customDie, faces: 3, values: []int{-1, 5, 2}, explode: explodeStandard, explodeAt: comparePoint{compareEqual, 5}}},
+	{"Term with registered named die", "3dBOON>=2", diceTerm{count: 3, kind: customDie, faces: 4, values: []int{0, 1, 1, 2}, countSuccesses: true, successAt: comparePoint{compareGreaterEqual, 2}}},
+	{"Term with explode and keep modifiers", "4d6!kh3", diceTerm{count: 4, faces: 6, selection: keepHighest, selectCount: 3, explode: explodeStandard, explodeAt: comparePoint{compareEqual, 6}}},
+}
+
+func TestParseDiceTermWithInvalidInput(t *testing.T) {
+	for _, tc := range invalidDiceTermTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseDiceTerm(tc.in)
+			if err == nil {
+				t.Fatalf("Expected err to not be nil but it was.\n")
+			}
+		})
+	}
+}
+
+func TestParseDiceTermWithValidInput(t *testing.T) {
+	if err := RegisterDie("BOON", []int{0, 1, 1, 2}); err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+
+	for _, tc := range validDiceTermTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			term, err := parseDiceTerm(tc.in)
+			if err != nil {
+				t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+			}
+
+			if !reflect.DeepEqual(term, tc.expected) {
+				t.Fatalf("Expected term %+v but found %+v.\n", tc.expected, term)
+			}
+		})
+	}
+}
+
+func TestRollKeepsExpectedDice(t *testing.T) {
+	terms := []diceTerm{
+		{count: 4, faces: 6, selection: keepHighest, selectCount: 3},
+		{count: 5, faces: 20, selection: keepLowest, selectCount: 2},
+		{count: 4, faces: 6, selection: dropLowest, selectCount: 1},
+		{count: 5, faces: 10, selection: dropHighest, selectCount: 2},
+	}
+
+	for _, term := range terms {
+		roll, err := term.roll(defaultOptions())
+		if err != nil {
+			t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+		}
+
+		kept, total := 0, 0
+		for _, d := range roll.dice {
+			if d.Dropped {
+				continue
+			}
+			kept++
+			total += d.Value
+
+			for _, other := range roll.dice {
+				if !other.Dropped {
+					continue
+				}
+				preferHighest := term.selection == keepHighest || term.selection == dropLowest
+				if preferHighest && other.Value > d.Value {
+					t.Fatalf("Dropped die %d is higher than kept die %d.\n", other.Value, d.Value)
+				}
+				if !preferHighest && other.Value < d.Value {
+					t.Fatalf("Dropped die %d is lower than kept die %d.\n", other.Value, d.Value)
+				}
+			}
+		}
+
+		expectedKept := term.selectCount
+		if term.selection == dropHighest || term.selection == dropLowest {
+			expectedKept = term.count - term.selectCount
+		}
+		if kept != expectedKept {
+			t.Fatalf("Expected %d kept dice but found %d.\n", expectedKept, kept)
+		}
+
+		if total != roll.total {
+			t.Fatalf("Expected total %d to be the sum of kept dice %d.\n", roll.total, total)
+		}
+	}
+}
+
+func TestRollKeepsDiceAtTheLimitsOfInt(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected int
+	}{
+		{"2d{-9000000000000000000,9000000000000000000}kh1", 9000000000000000000},
+		{"2d{-9000000000000000000,9000000000000000000}kl1", -9000000000000000000},
+	}
+
+	for _, tc := range cases {
+		expression, err := Compile([]byte(tc.input))
+		if err != nil {
+			t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+		}
+
+		for range 50 {
+			res, err := expression.Roll()
+			if err != nil {
+				t.Fatalf("Expected error to be nil but was present with message %s\n", err.Error())
+			}
+			if dice := res.Dice; dice[0].Value != dice[1].Value && res.Value.Int() != tc.expected {
+				t.Fatalf("Expected %s to keep %d but found %d.\n", tc.input, tc.expected, res.Value.Int())
+			}
+		}
+	}
+}
+
+func TestRollExplodes(t *testing.T) {
+	standard := diceTerm{count: 1, faces: 2, explode: explodeStandard, explodeAt: comparePoint{compareLess, 3}}
+	opts := defaultOptions()
+	opts.maxExplosions = 5
+	_, err := standard.roll(opts)
+	if err == nil {
+		t.Fatalf("Expected err to not be nil when every face explodes but it was.\n")
+	}
+
+	compound := diceTerm{count: 3, faces: 6, explode: explodeCompound, explodeAt: comparePoint{compareEqual, 6}}
+	roll, err := compound.roll(defaultOptions())
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+	if len(roll.dice) != compound.count {
+		t.Fatalf("Expected compounding dice to stay at %d dice but found %d.\n", compound.count, len(roll.dice))
+	}
+	for _, d := range roll.dice {
+		if d.Exploded != (d.Value >= 6) {
+			t.Fatalf("Die with value %d has exploded = %t.\n", d.Value, d.Exploded)
+		}
+	}
+
+	penetrate := diceTerm{count: 20, faces: 2, explode: explodePenetrate, explodeAt: comparePoint{compareEqual, 2}}
+	roll, err = penetrate.roll(defaultOptions())
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+	for i, d := range roll.dice {
+		if i > 0 && roll.dice[i-1].Exploded && (d.Value < 0 || d.Value > 1) {
+			t.Fatalf("Penetrating die should be reduced by 1 but found %d.\n", d.Value)
+		}
+	}
+}
+
+func TestRollRerolls(t *testing.T) {
+	always := diceTerm{count: 1, faces: 2, reroll: rerollAlways, rerollAt: comparePoint{compareLessEqual, 2}}
+	opts := defaultOptions()
+	opts.maxRerolls = 5
+	_, err := always.roll(opts)
+	if err == nil {
+		t.Fatalf("Expected err to not be nil when every face is rerolled but it was.\n")
+	}
+
+	always = diceTerm{count: 50, faces: 6, reroll: rerollAlways, rerollAt: comparePoint{compareLess, 3}}
+	roll, err := always.roll(defaultOptions())
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+	for _, d := range roll.dice {
+		if d.Value < 3 {
+			t.Fatalf("Die with value %d should have been rerolled.\n", d.Value)
+		}
+		for _, r := range d.Rerolled {
+			if r >= 3 {
+				t.Fatalf("Rerolled value %d did not match the reroll compare point.\n", r)
+			}
+		}
+	}
+
+	once := diceTerm{count: 50, faces: 1, reroll: rerollOnce, rerollAt: comparePoint{compareEqual, 1}}
+	roll, err = once.roll(defaultOptions())
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+	for _, d := range roll.dice {
+		if len(d.Rerolled) != 1 || d.Rerolled[0] != 1 || d.Value != 1 {
+			t.Fatalf("Expected die to be rerolled exactly once but found value %d and rerolls %v.\n", d.Value, d.Rerolled)
+		}
+	}
+}
+
+func TestRollCountsSuccesses(t *testing.T) {
+	term := diceTerm{count: 20, faces: 10, countSuccesses: true, successAt: comparePoint{compareGreaterEqual, 8}, countFailures: true, failureAt: comparePoint{compareEqual, 1}}
+	roll, err := term.roll(defaultOptions())
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+
+	if roll.pool == nil {
+		t.Fatalf("Expected roll to have a pool result but it was nil.\n")
+	}
+
+	successes, failures := 0, 0
+	for _, d := range roll.dice {
+		if d.Success != (d.Value >= 8) {
+			t.Fatalf("Die with value %d has success = %t.\n", d.Value, d.Success)
+		}
+		if d.Failure != (d.Value == 1) {
+			t.Fatalf("Die with value %d has failure = %t.\n", d.Value, d.Failure)
+		}
+		if d.Success {
+			successes++
+		}
+		if d.Failure {
+			failures++
+		}
+	}
+
+	if roll.pool.Successes != successes || roll.pool.Failures != failures {
+		t.Fatalf("Expected pool of %d successes and %d failures but found %+v.\n", successes, failures, *roll.pool)
+	}
+
+	if roll.total != successes-failures {
+		t.Fatalf("Expected total %d to be successes minus failures %d.\n", roll.total, successes-failures)
+	}
+}
+
+type registerDieTestCase struct {
+	name  string
+	die   string
+	faces []int
+}
+
+var invalidRegisterDieTestCases = []registerDieTestCase{
+	{"Empty name returns error", "", []int{1}},
+	{"Fate name is reserved and returns error", "F", []int{1}},
+	{"Lowercase name returns error", "Boon", []int{1}},
+	{"No faces returns error", "BOON", []int{}},
+}
+
+func TestRegisterDieWithInvalidInput(t *testing.T) {
+	for _, tc := range invalidRegisterDieTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := RegisterDie(tc.die, tc.faces); err == nil {
+				t.Fatalf("Expected err to not be nil but it was.\n")
+			}
+		})
+	}
+}
+
+func TestRollCustomDice(t *testing.T) {
+	term, err := parseDiceTerm("20d{2,3,5}")
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+
+	roll, err := term.roll(defaultOptions())
+	if err != nil {
+		t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
+	}
+	for _, d := range roll.dice {
+		if d.Value != 2 && d.Value != 3 && d.Value != 5 {
+			t.Fatalf("Die with value %d is not one of the listed faces.\n", d.Value)
+		}
+	}
+}
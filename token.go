@@ -1,10 +1,9 @@
 package dice
 
 import (
+	"errors"
 	"fmt"
-	"math/rand/v2"
 	"strconv"
-	"strings"
 )
 
 type tokenType int
@@ -14,49 +13,72 @@ const (
 	operator
 	dice
 	literal
+	unaryOperator // set by the parser on + and - operators in prefix position
+	identifier
+	invalid // text that couldn't be scanned, kept by a parser that recovers from syntax errors
 )
 const eofByte = byte(0)
 
+// String returns the name of the token kind used in error messages
+func (kind tokenType) String() string {
+	switch kind {
+	case eof:
+		return "EOF"
+	case operator:
+		return "operator"
+	case dice:
+		return "dice"
+	case literal:
+		return "literal"
+	case unaryOperator:
+		return "unary operator"
+	case identifier:
+		return "identifier"
+	case invalid:
+		return "invalid"
+	default:
+		return fmt.Sprintf("tokenType(%d)", int(kind))
+	}
+}
+
 type token struct {
 	kind  tokenType
 	value string
+	start int // offset of the first byte of the token in the buffer
+	end   int // offset just past the last byte of the token in the buffer
 }
 
-func (token token) evaluate() (int, error) {
+func (token token) evaluate(opts options) (int, error) {
 	switch token.kind {
 	case dice:
-		idx := strings.Index(token.value, "d")
-		if idx == -1 {
-			idx = strings.Index(token.value, "D")
-			if idx == -1 {
-				return 0, fmt.Errorf("Did not find 'd' or 'D' in token with type = dice and value = %s", token.value)
-			}
-		}
-
-		count := 1
-		var err error
-
-		if idx != 0 {
-			count, err = strconv.Atoi(token.value[:idx])
-			if err != nil {
-				return 0, err
-			}
-		}
-
-		faces, err := strconv.Atoi(token.value[idx+1:])
+		roll, err := token.roll(opts)
 		if err != nil {
 			return 0, err
 		}
+		return roll.total, nil
 
-		total := 0
-		for range count {
-			total += (rand.IntN(faces) + 1)
-		}
-		return total, nil
-
+	case invalid:
+		return 0, errorf(ErrSyntax, "Expression has a syntax error at offset %d.", token.start)
 	case literal:
-		return strconv.Atoi(string(token.value))
+		n, err := strconv.Atoi(token.value)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, errorf(ErrOverflow, "Number %s is too large.", token.value)
+		}
+		return n, err
 	default:
-		return 0, fmt.Errorf("Token type %d does not support evaluate.", token.kind)
+		return 0, fmt.Errorf("Token type %s does not support evaluate.", token.kind)
+	}
+}
+
+// roll parses the value of a dice token and rolls it
+func (token token) roll(opts options) (diceRoll, error) {
+	if token.kind != dice {
+		return diceRoll{}, fmt.Errorf("Token type %s does not support roll.", token.kind)
+	}
+
+	term, err := parseDiceTerm(token.value)
+	if err != nil {
+		return diceRoll{}, err
 	}
+	return term.roll(opts)
 }
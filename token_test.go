@@ -12,20 +12,22 @@ type evaluateTestCase struct {
 }
 
 var invalidEvaluateTestCases = []evaluateTestCase{
-	{"Token with kind of eof returns error", token{eof, ""}, 0, 0},
-	{"Token with kind of operator returns error", token{operator, "+"}, 0, 0},
-	{"Token with kind of dice and without 'd/D' character returns error", token{dice, "6"}, 0, 0},
+	{"Token with kind of eof returns error", token{eof, "", 0, 0}, 0, 0},
+	{"Token with kind of operator returns error", token{operator, "+", 0, 0}, 0, 0},
+	{"Token with kind of dice and without 'd/D' character returns error", token{dice, "6", 0, 0}, 0, 0},
 	// atoi actually fails this test because it splits on for d/D and then passes test to atoi as number
-	{"Token with kind of dice and multiple 'd/D' characters returns error", token{dice, "1Dd6"}, 0, 0},
-	{"Token with kind of dice and multiple 'd/D' characters and no 'count' prefix number returns error", token{dice, "Dd6"}, 0, 0},
-	{"Token with kind of dice and multiple 'd/D' characters throughout the value returns error", token{dice, "1D2d6D"}, 0, 0},
-	{"Token with kind of literal and non-digit characters returns error", token{dice, "61d11e"}, 0, 0},
+	{"Token with kind of dice and multiple 'd/D' characters returns error", token{dice, "1Dd6", 0, 0}, 0, 0},
+	{"Token with kind of dice and multiple 'd/D' characters and no 'count' prefix number returns error", token{dice, "Dd6", 0, 0}, 0, 0},
+	{"Token with kind of dice and multiple 'd/D' characters throughout the value returns error", token{dice, "1D2d6D", 0, 0}, 0, 0},
+	{"Token with kind of literal and non-digit characters returns error", token{dice, "61d11e", 0, 0}, 0, 0},
+	{"Token with kind of dice and zero faces returns error", token{dice, "1d0", 0, 0}, 0, 0},
+	{"Token with kind of dice keeping more dice than rolled returns error", token{dice, "2d6kh3", 0, 0}, 0, 0},
 }
 
 func TestEvaluateWithInvalidToken(t *testing.T) {
 	for _, tc := range invalidEvaluateTestCases {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := tc.in.evaluate()
+			_, err := tc.in.evaluate(defaultOptions())
 			if err == nil {
 				t.Fatalf("Expected err to not be nil but it was.\n")
 			}
@@ -34,18 +36,25 @@ func TestEvaluateWithInvalidToken(t *testing.T) {
 }
 
 var validEvaluateTestCases = []evaluateTestCase{
-	{"Token with kind of literal single digit returns int value", token{literal, "1"}, 1, 1},
-	{"Token with kind of literal multiple digits returns int value", token{literal, "1111"}, 1111, 1111},
-	{"Token with kind of dice with value d{faces} returns int between 1 and {faces}", token{dice, "d4"}, 1, 4},
-	{"Token with kind of dice with value D{faces} returns int between 1 and {faces}", token{dice, "D4"}, 1, 4},
-	{"Token with kind of dice with value {count}*d{faces} returns int between {count} and {count}*{faces}", token{dice, "3d2"}, 3, 6},
-	{"Token with kind of dice with value {count}*D{faces} returns int between {count} and {count}*{faces}", token{dice, "3d2"}, 3, 6},
+	{"Token with kind of literal single digit returns int value", token{literal, "1", 0, 0}, 1, 1},
+	{"Token with kind of literal multiple digits returns int value", token{literal, "1111", 0, 0}, 1111, 1111},
+	{"Token with kind of dice with value d{faces} returns int between 1 and {faces}", token{dice, "d4", 0, 0}, 1, 4},
+	{"Token with kind of dice with value D{faces} returns int between 1 and {faces}", token{dice, "D4", 0, 0}, 1, 4},
+	{"Token with kind of dice with value {count}*d{faces} returns int between {count} and {count}*{faces}", token{dice, "3d2", 0, 0}, 3, 6},
+	{"Token with kind of dice with value {count}*D{faces} returns int between {count} and {count}*{faces}", token{dice, "3d2", 0, 0}, 3, 6},
+	{"Token with kind of dice with keep highest returns int between {keep} and {keep}*{faces}", token{dice, "4d6kh3", 0, 0}, 3, 18},
+	{"Token with kind of dice with keep lowest returns int between {keep} and {keep}*{faces}", token{dice, "2d20kl1", 0, 0}, 1, 20},
+	{"Token with kind of dice with reroll returns int between the lowest kept face and {count}*{faces}", token{dice, "2d6r<2", 0, 0}, 4, 12},
+	{"Token with kind of dice with success and failure compare points returns int between -{count} and {count}", token{dice, "10d10>=8f1", 0, 0}, -10, 10},
+	{"Token with kind of dice with fate dice returns int between -{count} and {count}", token{dice, "4dF", 0, 0}, -4, 4},
+	{"Token with kind of dice with percentile dice returns int between 1 and 100", token{dice, "d%", 0, 0}, 1, 100},
+	{"Token with kind of dice with drop highest returns int between {count-drop} and {count-drop}*{faces}", token{dice, "5d10dh2", 0, 0}, 3, 30},
 }
 
 func TestEvaluateWithValidToken(t *testing.T) {
 	for _, tc := range validEvaluateTestCases {
 		t.Run(tc.name, func(t *testing.T) {
-			res, err := tc.in.evaluate()
+			res, err := tc.in.evaluate(defaultOptions())
 			if err != nil {
 				t.Fatalf("Expected err to be nil but err had message %s.\n", err.Error())
 			}
@@ -60,3 +69,12 @@ func TestEvaluateWithValidToken(t *testing.T) {
 		})
 	}
 }
+
+func TestTokenTypeString(t *testing.T) {
+	names := map[tokenType]string{eof: "EOF", operator: "operator", dice: "dice", literal: "literal", unaryOperator: "unary operator", identifier: "identifier", invalid: "invalid", tokenType(42): "tokenType(42)"}
+	for kind, expected := range names {
+		if kind.String() != expected {
+			t.Fatalf("Expected token type name %s but found %s.\n", expected, kind.String())
+		}
+	}
+}
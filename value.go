@@ -0,0 +1,42 @@
+package dice
+
+import "strconv"
+
+// Value is the result of evaluating an expression. It is either an integer or, for comparisons and logical operators, a boolean.
+type Value struct {
+	isBool bool
+	n      int
+}
+
+func intValue(n int) Value {
+	return Value{false, n}
+}
+
+func boolValue(b bool) Value {
+	if b {
+		return Value{true, 1}
+	}
+	return Value{true, 0}
+}
+
+// IsBool reports whether the value is a boolean
+func (v Value) IsBool() bool {
+	return v.isBool
+}
+
+// Int returns the integer value. Booleans return 1 for true and 0 for false.
+func (v Value) Int() int {
+	return v.n
+}
+
+// Bool returns the boolean value. Integers return true when they are not 0.
+func (v Value) Bool() bool {
+	return v.n != 0
+}
+
+func (v Value) String() string {
+	if v.isBool {
+		return strconv.FormatBool(v.Bool())
+	}
+	return strconv.Itoa(v.n)
+}
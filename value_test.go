@@ -0,0 +1,44 @@
+package dice
+
+import (
+	"testing"
+)
+
+type valueTestCase struct {
+	name           string
+	in             Value
+	expectedIsBool bool
+	expectedInt    int
+	expectedBool   bool
+	expectedString string
+}
+
+var valueTestCases = []valueTestCase{
+	{"Integer value", intValue(-3), false, -3, true, "-3"},
+	{"Zero integer value is false", intValue(0), false, 0, false, "0"},
+	{"True boolean value is 1", boolValue(true), true, 1, true, "true"},
+	{"False boolean value is 0", boolValue(false), true, 0, false, "false"},
+}
+
+func TestValue(t *testing.T) {
+	for _, tc := range valueTestCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.in.IsBool() != tc.expectedIsBool {
+				t.Fatalf("Expected IsBool() to be %t but was %t.\n", tc.expectedIsBool, tc.in.IsBool())
+			}
+			if tc.in.Int() != tc.expectedInt {
+				t.Fatalf("Expected Int() to be %d but was %d.\n", tc.expectedInt, tc.in.Int())
+			}
+			if tc.in.Bool() != tc.expectedBool {
+				t.Fatalf("Expected Bool() to be %t but was %t.\n", tc.expectedBool, tc.in.Bool())
+			}
+			if tc.in.String() != tc.expectedString {
+				t.Fatalf("Expected String() to be %s but was %s.\n", tc.expectedString, tc.in.String())
+			}
+		})
+	}
+
+	if intValue(1) == boolValue(true) {
+		t.Fatalf("Expected the integer 1 and the boolean true to be different values.\n")
+	}
+}